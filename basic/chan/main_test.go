@@ -3,11 +3,16 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"os"
 	"sync"
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 // Note how the resultStream's lifecycle is encapsulated within the chanOwner
 // func.
 func TestOwnerConsumerResponsibility(t *testing.T) {
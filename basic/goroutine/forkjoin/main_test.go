@@ -2,10 +2,15 @@ package main
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"sync"
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 func TestJoinPointWithWaitGroup(t *testing.T) {
 	var wg sync.WaitGroup
 	sayHello := func() {
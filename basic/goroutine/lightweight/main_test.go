@@ -3,11 +3,17 @@ package main
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"runtime"
 	"sync"
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	// TestSize leaks its goroutines on purpose to measure them.
+	leaktest.Main(m, "TestSize.func")
+}
+
 func TestSize(_ *testing.T) {
 	memConsumed := func() uint64 {
 		runtime.GC()
@@ -2,9 +2,14 @@ package main
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 func TestDataRace(_ *testing.T) {
 	var data int
 	go func() {
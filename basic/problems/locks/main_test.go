@@ -3,12 +3,18 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	// TestLivelock's cadence ticks forever, it has no done to stop it.
+	leaktest.Main(m, "TestLivelock.func1")
+}
+
 // TestDeallock show how deadlock is triggered when conffman conditions are met.
 func TestDeadlock(t *testing.T) {
 	t.Skip()
@@ -4,9 +4,14 @@ package main
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 func TestCriticalSection(_ *testing.T) {
 	var data int
 	go func() { data++ }() 						// 1
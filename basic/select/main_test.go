@@ -2,10 +2,15 @@ package main
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"testing"
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 func TestBlockWhenNoneReady(_ *testing.T) {
 	start := time.Now()
 	c := make(chan interface{})
@@ -2,11 +2,16 @@ package main
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"sync"
 	"testing"
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 // This code shows unicasting with Signal().
 //
 // Here we have a queue of fixed length 2, and 10 items we want to push onto the
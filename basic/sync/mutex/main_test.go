@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"math"
 	"os"
 	"sync"
@@ -10,6 +11,10 @@ import (
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 // Mutex: mutual exclusion.
 //
 // Mutex provides a concurrent-safe way to express exclusive access to these shared
@@ -2,10 +2,15 @@ package main
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"sync"
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 // Pool’s primary interface is its Get method. When called, Get will first check
 // whether there are any available instances within the pool to return to the
 // caller, and if not, call its New member variable to create a new one. When
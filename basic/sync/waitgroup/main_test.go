@@ -14,11 +14,16 @@ package main
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"sync"
 	"testing"
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 func TestTrackEach(_ *testing.T) {
 	var wg sync.WaitGroup
 
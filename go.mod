@@ -2,4 +2,4 @@ module learn/go/concurrency
 
 go 1.18
 
-require golang.org/x/time v0.3.0 // indirect
+require golang.org/x/time v0.3.0
@@ -0,0 +1,147 @@
+// Package leaktest find goroutines a test left behind.
+//
+// cancellation/ "proves" a goroutine leaked by noting a deferred Println never
+// printed. leaktest does it for real: it snapshots the live goroutines before
+// a test, and after it, reports those which are still alive after a grace
+// period, with their stacks.
+//
+// Per test:
+//
+//		func TestX(t *testing.T) {
+//			leaktest.Check(t)
+//			...
+//		}
+//
+// Or for every test of a package at once:
+//
+//		func TestMain(m *testing.M) {
+//			leaktest.Main(m)
+//		}
+package leaktest
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Grace is how long goroutines are given to exit after the test, since one
+// which is told to stop (e.g. by closing done in a defer) needs a moment to
+// get scheduled and return.
+var Grace = 2 * time.Second
+
+// ignored are goroutines of the runtime and the testing package itself.
+var ignored = []string{
+	"testing.(*M).startAlarm",
+	"testing.(*T).Run",
+	"testing.runTests",
+	"testing.tRunner",
+	"os/signal.signal_recv",
+	"runtime.ensureSigM",
+	"runtime/trace.Start",
+}
+
+// Goroutine is a goroutine as seen in a stack dump.
+type Goroutine struct {
+	ID    int
+	Stack string
+}
+
+// Snapshot return the goroutines alive now, by ID.
+func Snapshot() map[int]Goroutine {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	goroutines := make(map[int]Goroutine)
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		// The first line is like "goroutine 18 [chan receive]:".
+		fields := strings.Fields(stack)
+		if len(fields) < 2 || fields[0] != "goroutine" {
+			continue
+		}
+		id, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		goroutines[id] = Goroutine{ID: id, Stack: stack}
+	}
+	return goroutines
+}
+
+// Leaked return the goroutines alive now which are not in before, waiting up
+// to grace for them to exit. Goroutines whose stack contains any of ignore
+// are not counted, e.g. a known leak a test demonstrates on purpose.
+func Leaked(before map[int]Goroutine, grace time.Duration, ignore ...string) []Goroutine {
+	deadline := time.Now().Add(grace)
+	for {
+		var leaked []Goroutine
+		for id, g := range Snapshot() {
+			if _, ok := before[id]; ok || matches(g.Stack, ignored) || matches(g.Stack, ignore) {
+				continue
+			}
+			leaked = append(leaked, g)
+		}
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			sort.Slice(leaked, func(i, j int) bool { return leaked[i].ID < leaked[j].ID })
+			return leaked
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func matches(stack string, substrings []string) bool {
+	for _, s := range substrings {
+		if strings.Contains(stack, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check fail t if goroutines started during the test are still alive Grace
+// after it (and its deferred calls) finished. See Leaked for ignore.
+func Check(t testing.TB, ignore ...string) {
+	t.Helper()
+	before := Snapshot()
+	t.Cleanup(func() {
+		if leaked := Leaked(before, Grace, ignore...); len(leaked) > 0 {
+			t.Errorf("%s", report(leaked))
+		}
+	})
+}
+
+// Main run the tests of m, then fail if goroutines started by them are still
+// alive Grace after. It's meant to be the whole of TestMain. See Leaked for
+// ignore.
+func Main(m *testing.M, ignore ...string) {
+	before := Snapshot()
+	code := m.Run()
+	if code == 0 {
+		if leaked := Leaked(before, Grace, ignore...); len(leaked) > 0 {
+			fmt.Fprintln(os.Stderr, report(leaked))
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
+
+func report(leaked []Goroutine) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "leaktest: %d goroutine(s) leaked:\n", len(leaked))
+	for _, g := range leaked {
+		fmt.Fprintf(&b, "\n%s\n", g.Stack)
+	}
+	return b.String()
+}
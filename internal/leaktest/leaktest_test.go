@@ -0,0 +1,36 @@
+package leaktest
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLeaked(t *testing.T) {
+	before := Snapshot()
+
+	block := make(chan interface{})
+	go func() { <-block }() // leaks until block is closed
+	go func() {}()          // exits right away
+
+	leaked := Leaked(before, 50*time.Millisecond)
+	if len(leaked) != 1 || !strings.Contains(leaked[0].Stack, "TestLeaked.func1") {
+		t.Errorf("got leaked %v, want the goroutine blocked on block", leaked)
+	}
+	if leaked := Leaked(before, 50*time.Millisecond, "TestLeaked.func1"); len(leaked) != 0 {
+		t.Errorf("got leaked %v, want it ignored", leaked)
+	}
+
+	close(block)
+	if leaked := Leaked(before, time.Second); len(leaked) != 0 {
+		t.Errorf("got leaked %v after closing block, want none", leaked)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	Check(t)
+
+	done := make(chan interface{})
+	defer close(done)
+	go func() { <-done }() // exits with the deferred close, before Check look.
+}
@@ -1,7 +1,9 @@
 package bridge
 
 import (
+	"context"
 	. "learn/go/concurrency/pattern/ordone"
+	"sync"
 )
 
 // bridge implement the Bridge Channel pattern.
@@ -12,13 +14,9 @@ func Bridge(
 	valStream := make(chan interface{})
 	go func() {
 		defer close(valStream)
-		for stream := range chanStream {
-			select {
-			case <-done:
-				return
-			default:
-			}
-
+		// chanStream is wrapped too: a chanStream which is never closed would
+		// otherwise hold this goroutine forever after done.
+		for stream := range OrDone(done, chanStream) {
 			for v := range OrDone(done, stream) {
 				select {
 				case <-done:
@@ -29,3 +27,104 @@ func Bridge(
 	}()
 	return valStream
 }
+
+// BridgeCtx is Bridge driven by ctx instead of a done channel. valStream is
+// closed when chanStream is drained or ctx is done, ctx.Err() tells which.
+func BridgeCtx[T any](
+	ctx context.Context,
+	chanStream <-chan <-chan T,
+) <-chan T {
+	valStream := make(chan T)
+	go func() {
+		defer close(valStream)
+		for stream := range OrDoneCtx(ctx, chanStream) {
+			for v := range OrDoneCtx(ctx, stream) {
+				select {
+				case <-ctx.Done():
+				case valStream <- v:
+				}
+			}
+		}
+	}()
+	return valStream
+}
+
+// Tagged is a value v read from the stream at index Stream in chanStream, see
+// BridgeTagged.
+type Tagged[T any] struct {
+	Stream int
+	V      T
+}
+
+// BridgeConcurrent is Bridge which reads up to n streams of chanStream at
+// once, interleaving their values as they come. A slow stream then no longer
+// holds up values already available on later streams, e.g. a restarted ward
+// can send while the one it replaces is still winding down.
+//
+// With n of 1 it's Bridge: streams are drained strictly one after another.
+func BridgeConcurrent[T any](
+	done <-chan any,
+	chanStream <-chan <-chan T,
+	n int,
+) <-chan T {
+	return bridgeConcurrent(done, chanStream, n, func(_ int, v T) T { return v })
+}
+
+// BridgeTagged is BridgeConcurrent which tags every value with the index of
+// its stream, streams being numbered from 0 in the order chanStream sent
+// them.
+func BridgeTagged[T any](
+	done <-chan any,
+	chanStream <-chan <-chan T,
+	n int,
+) <-chan Tagged[T] {
+	return bridgeConcurrent(done, chanStream, n, func(i int, v T) Tagged[T] {
+		return Tagged[T]{Stream: i, V: v}
+	})
+}
+
+func bridgeConcurrent[T, U any](
+	done <-chan any,
+	chanStream <-chan <-chan T,
+	n int,
+	tag func(i int, v T) U,
+) <-chan U {
+	if n < 1 {
+		n = 1
+	}
+
+	valStream := make(chan U)
+	go func() {
+		defer close(valStream)
+
+		// A slot is acquired before a stream is read, and released once it's
+		// drained, so at most n streams are read at once.
+		slots := make(chan struct{}, n)
+		var wg sync.WaitGroup
+		defer wg.Wait() // Leave no reader behind.
+
+		i := 0
+		for stream := range OrDone(done, chanStream) {
+			select {
+			case <-done:
+				return
+			case slots <- struct{}{}:
+			}
+
+			wg.Add(1)
+			go func(i int, stream <-chan T) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				for v := range OrDone(done, stream) {
+					select {
+					case <-done:
+						return
+					case valStream <- tag(i, v):
+					}
+				}
+			}(i, stream)
+			i++
+		}
+	}()
+	return valStream
+}
@@ -1,10 +1,17 @@
 package bridge
 
 import (
+	"context"
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"testing"
+	"time"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 func TestBridge(_ *testing.T) {
 	genVals := func() <-chan <-chan interface{} {
 		chanStream := make(chan (<-chan interface{}))
@@ -24,3 +31,103 @@ func TestBridge(_ *testing.T) {
 		fmt.Println(v)
 	}
 }
+
+func TestBridgeCtx(t *testing.T) {
+	genVals := func() <-chan <-chan int {
+		chanStream := make(chan (<-chan int))
+		go func() {
+			defer close(chanStream)
+			for i := 0; i < 10; i++ {
+				stream := make(chan int, 1)
+				stream <- i
+				close(stream)
+				chanStream <- stream
+			}
+		}()
+		return chanStream
+	}
+
+	sum := 0
+	for v := range BridgeCtx(context.Background(), genVals()) {
+		sum += v
+	}
+	if sum != 45 {
+		t.Errorf("sum = %v, want 45", sum)
+	}
+}
+
+func TestBridgeTagged(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	slow, fast := make(chan int), make(chan int, 2)
+	fast <- 1
+	fast <- 2
+	close(fast)
+
+	chanStream := make(chan (<-chan int), 2)
+	chanStream <- slow
+	chanStream <- fast
+	close(chanStream)
+
+	valStream := BridgeTagged(done, chanStream, 2)
+	// fast's values get through while slow holds back, Bridge would wait.
+	for _, want := range []Tagged[int]{{1, 1}, {1, 2}} {
+		if got := <-valStream; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+	slow <- 0
+	close(slow)
+	if got, want := <-valStream, (Tagged[int]{0, 0}); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, ok := <-valStream; ok {
+		t.Error("valStream not closed")
+	}
+}
+
+func TestBridgeConcurrentBound(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	blocked1, blocked2, ready := make(chan int), make(chan int), make(chan int, 1)
+	ready <- 3
+	close(ready)
+
+	chanStream := make(chan (<-chan int), 3)
+	chanStream <- blocked1
+	chanStream <- blocked2
+	chanStream <- ready
+	close(chanStream)
+
+	valStream := BridgeConcurrent(done, chanStream, 2)
+	select {
+	case v := <-valStream:
+		t.Fatalf("got %v while 2 streams are being read already", v)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(blocked1)
+	if v := <-valStream; v != 3 {
+		t.Errorf("got %v, want 3", v)
+	}
+	close(blocked2)
+	if _, ok := <-valStream; ok {
+		t.Error("valStream not closed")
+	}
+}
+
+// TestBridgeDoneOpenChanStream shows Bridge exiting on done even while it's
+// waiting for a stream on a chanStream which is never closed, like the one
+// of doWorkFn in scale/healing.
+func TestBridgeDoneOpenChanStream(t *testing.T) {
+	leaktest.Check(t)
+
+	done := make(chan interface{})
+	valStream := Bridge(done, make(chan (<-chan interface{})))
+	close(done)
+	if _, ok := <-valStream; ok {
+		t.Error("valStream not closed after done")
+	}
+}
@@ -12,11 +12,17 @@ package main
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"math/rand"
 	"testing"
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	// TestReadNilLeak and TestWritingLeak leak on purpose, that's the point.
+	leaktest.Main(m, "TestReadNilLeak.func", "TestWritingLeak.func")
+}
+
 // TestReadNilLeak show how reading on nil caused goroutine leak.
 func TestReadNilLeak(_ *testing.T) {
 	doWork := func(strings <-chan string) <-chan interface{} {
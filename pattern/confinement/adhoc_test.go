@@ -2,9 +2,14 @@ package confinement
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 // TestAdHoc shows how adhoc confinement works by convention: data slice is
 // available from both the loopData func and the loop over then handleData
 // chan, but by convention we're only accessing it from the loopData func.
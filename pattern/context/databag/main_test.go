@@ -3,9 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 // TestDataBag shows the basic usage of context request-scoped data.
 //
 // Becuase context's Key and Value are both defined as interface{}, and we don't
@@ -3,9 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 type ctxKey int // unexported Key type, to avoid conflict with other packages.
 
 const (
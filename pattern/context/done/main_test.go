@@ -2,11 +2,16 @@ package done
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"sync"
 	"testing"
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 func printGreeting(done <-chan any) error {
 	greeting, err := genGreeting(done)
 	if err != nil {
@@ -3,11 +3,16 @@ package main
 import (
 	"context"
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"sync"
 	"testing"
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 func printGreeting(ctx context.Context) error {
 	greeting, err := genGreeting(ctx)
 	if err != nil {
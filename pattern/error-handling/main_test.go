@@ -2,10 +2,16 @@ package main
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
+	. "learn/go/concurrency/pattern/pipeline/stage"
 	"net/http"
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 // TestAntiPattern shows the anti pattern of error handling for concurrent
 // goroutines: The goroutine has been given no choice in the matter. It can’t
 // simply swallow the error, and so it does the only sensible thing: it prints
@@ -100,3 +106,38 @@ func TestEmbedErrorInResult(_ *testing.T) {
 	}
 }
 
+// TestResultStage shows the same idea with the reusable stage.Result: the
+// checker only has to report what happened, and the "too many errors" policy
+// is a stage of the pipeline instead of a hand-written loop.
+func TestResultStage(_ *testing.T) {
+	// check pretend to GET url, so this test doesn't need the network.
+	check := func(url string) (string, error) {
+		if url == "https://bing.com" {
+			return "200 OK", nil
+		}
+		return "", fmt.Errorf("Get %q: no such host", url)
+	}
+
+	done := make(chan interface{})
+	defer close(done)
+
+	urls := make(chan string)
+	go func() {
+		defer close(urls)
+		for _, url := range []string{"https://bing.com", "https://badhost", "a", "b", "c"} {
+			select {
+			case <-done:
+				return
+			case urls <- url:
+			}
+		}
+	}()
+
+	for result := range AbortAfter(done, Try(done, urls, check), 3) {
+		if result.Error != nil {
+			fmt.Printf("error: %v\n", result.Error)
+			continue
+		}
+		fmt.Printf("Response: %v\n", result.Value)
+	}
+}
@@ -0,0 +1,162 @@
+package fan
+
+import (
+	. "learn/go/concurrency/pattern/ordone"
+	"reflect"
+	"sync"
+)
+
+// FanIn multiplex channels into one. Items are sent in whatever order they
+// arrive, see stage.OrderedMap when input order matters.
+func FanIn[T any](
+	done <-chan any,
+	channels ...<-chan T,
+) <-chan T {
+	// In a nutshell, fanning in implementation involves creating the
+	// multiplexed(joined together) channel that consumers will read from,
+	// then spinning up one goroutine for each incoming channel, and one
+	// goroutine to close the multiplexed channel when the incoming
+	// channels have all been closed. Since we’re going to be creating a
+	// goroutine that is waiting on N other goroutines to complete, it
+	// makes sense to create a sync.WaitGroup to coordinate things. The
+	// multiplex function also notifies the WaitGroup that it’s done.
+
+	// Use wg to wait until all channels have been drained.
+	var wg sync.WaitGroup
+
+	multiplexedStream := make(chan T)
+	multiplex := func(c <-chan T) {
+		defer wg.Done()
+		for i := range OrDone(done, c) {
+			select {
+			case <-done:
+				return
+			case multiplexedStream <- i:
+			}
+		}
+	}
+
+	// Select from all the channels.
+	wg.Add(len(channels))
+	for _, c := range channels {
+		go multiplex(c)
+	}
+
+	// Wait for all the reads to complete.
+	go func() {
+		wg.Wait()
+		close(multiplexedStream)
+	}()
+
+	return multiplexedStream
+}
+
+type strategyKind int
+
+const (
+	roundRobin strategyKind = iota
+	leastLoaded
+	keyHash
+)
+
+// Strategy tells FanOut which output an item goes to.
+type Strategy[T any] struct {
+	kind strategyKind
+	key  func(T) string
+}
+
+// RoundRobin send items to the outputs in turn. A slow worker holds up the
+// others once its turn comes again.
+func RoundRobin[T any]() Strategy[T] {
+	return Strategy[T]{kind: roundRobin}
+}
+
+// LeastLoaded send each item to whichever output is read first, i.e. to an
+// idle worker. This is what starting several workers on one shared channel
+// does, as primeFinder in main_test.go.
+func LeastLoaded[T any]() Strategy[T] {
+	return Strategy[T]{kind: leastLoaded}
+}
+
+// KeyHash send all items with the same key to the same output. A worker reads
+// its output in order, so per-key ordering is preserved while the keys are
+// spread over all workers. A slow key holds up the keys sharing its output.
+//
+// Keys are placed on a consistent hash ring, so changing n only moves about
+// 1/n of them to another output, see hashRing.
+func KeyHash[T any](key func(T) string) Strategy[T] {
+	return Strategy[T]{kind: keyHash, key: key}
+}
+
+// FanOut partition in into n outputs according to strategy, one per worker.
+// The workers results can then be joined back by FanIn.
+func FanOut[T any](
+	done <-chan any,
+	in <-chan T,
+	n int,
+	strategy Strategy[T],
+) []<-chan T {
+	if n < 1 {
+		n = 1
+	}
+
+	outs := make([]chan T, n)
+	readOnly := make([]<-chan T, n)
+	for i := range outs {
+		outs[i] = make(chan T)
+		readOnly[i] = outs[i]
+	}
+
+	var ring *hashRing
+	if strategy.kind == keyHash {
+		ring = newHashRing(n, hashRingReplicas)
+	}
+
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+
+		next := 0
+		for v := range OrDone(done, in) {
+			var out chan T
+			switch strategy.kind {
+			case roundRobin:
+				out = outs[next]
+				next = (next + 1) % n
+			case keyHash:
+				out = outs[ring.shard(strategy.key(v))]
+			case leastLoaded:
+				if !sendAny(done, outs, v) {
+					return
+				}
+				continue
+			}
+
+			select {
+			case <-done:
+				return
+			case out <- v:
+			}
+		}
+	}()
+	return readOnly
+}
+
+// sendAny send v to whichever of outs is ready first. It returns false if
+// done was closed first.
+func sendAny[T any](done <-chan any, outs []chan T, v T) bool {
+	cases := make([]reflect.SelectCase, 0, len(outs)+1)
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(done)})
+	for _, out := range outs {
+		cases = append(cases, reflect.SelectCase{
+			Dir:  reflect.SelectSend,
+			Chan: reflect.ValueOf(out),
+			Send: reflect.ValueOf(&v).Elem(), // Valid even for a nil interface v.
+		})
+	}
+	chosen, _, _ := reflect.Select(cases)
+	return chosen != 0
+}
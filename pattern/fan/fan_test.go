@@ -0,0 +1,139 @@
+package fan
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// slice send values, then close.
+func slice[T any](done <-chan any, values ...T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for _, v := range values {
+			select {
+			case <-done:
+				return
+			case out <- v:
+			}
+		}
+	}()
+	return out
+}
+
+// collectAll read every output concurrently until they're all closed.
+func collectAll[T any](outs []<-chan T) [][]T {
+	got := make([][]T, len(outs))
+	var wg sync.WaitGroup
+	wg.Add(len(outs))
+	for i, out := range outs {
+		go func(i int, out <-chan T) {
+			defer wg.Done()
+			for v := range out {
+				got[i] = append(got[i], v)
+			}
+		}(i, out)
+	}
+	wg.Wait()
+	return got
+}
+
+func TestFanIn(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	var got []int
+	for v := range FanIn(done, slice(done, 1, 2), slice(done, 3), slice[int](done)) {
+		got = append(got, v)
+	}
+	sort.Ints(got)
+	if fmt.Sprint(got) != "[1 2 3]" {
+		t.Errorf("got %v, want [1 2 3]", got)
+	}
+}
+
+func TestFanOutRoundRobin(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	outs := FanOut(done, slice(done, 0, 1, 2, 3, 4, 5, 6), 3, RoundRobin[int]())
+	if got := fmt.Sprint(collectAll(outs)); got != "[[0 3 6] [1 4] [2 5]]" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestFanOutLeastLoaded(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	outs := FanOut(done, slice(done, 0, 1, 2, 3, 4, 5, 6), 3, LeastLoaded[int]())
+	var got []int
+	for _, vs := range collectAll(outs) {
+		got = append(got, vs...)
+	}
+	sort.Ints(got)
+	if fmt.Sprint(got) != "[0 1 2 3 4 5 6]" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestFanOutKeyHash(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	type event struct {
+		user string
+		seq  int
+	}
+	var events []event
+	for seq := 0; seq < 20; seq++ {
+		events = append(events, event{fmt.Sprint("user", seq%5), seq})
+	}
+
+	outs := FanOut(done, slice(done, events...), 3, KeyHash(func(e event) string { return e.user }))
+	outOf := make(map[string]int) // user to the output its events went to.
+	for i, es := range collectAll(outs) {
+		last := make(map[string]int)
+		for _, e := range es {
+			if j, ok := outOf[e.user]; ok && j != i {
+				t.Errorf("%v went to outputs %d and %d", e.user, j, i)
+			}
+			outOf[e.user] = i
+			if seq, ok := last[e.user]; ok && seq > e.seq {
+				t.Errorf("%v: seq %d after %d", e.user, e.seq, seq)
+			}
+			last[e.user] = e.seq
+		}
+	}
+	if len(outOf) != 5 {
+		t.Errorf("got %d users, want 5", len(outOf))
+	}
+}
+
+func TestHashRingRemap(t *testing.T) {
+	const keys = 10000
+	four, five := newHashRing(4, hashRingReplicas), newHashRing(5, hashRingReplicas)
+
+	moved := 0
+	perShard := make([]int, 5)
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprint("key", i)
+		s := five.shard(key)
+		perShard[s]++
+		if s != four.shard(key) {
+			moved++
+		}
+	}
+
+	// Ideally 1/5 of the keys move, all of them to the new shard.
+	if frac := float64(moved) / keys; frac > 0.3 {
+		t.Errorf("%.2f of the keys moved, want about 0.2", frac)
+	}
+	for s, n := range perShard {
+		if n < keys/5/2 {
+			t.Errorf("shard %d got %d keys, want about %d", s, n, keys/5)
+		}
+	}
+}
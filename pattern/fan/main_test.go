@@ -1,16 +1,21 @@
 // Try to find prime numbers in a series of random numbers, using a slow stage.
-package main
+package fan_test
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
+	. "learn/go/concurrency/pattern/fan"
 	. "learn/go/concurrency/pattern/pipeline/stage"
 	mathrand "math/rand"
 	"runtime"
-	"sync"
 	"testing"
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 func rand() interface{} {
 	return mathrand.Intn(50000000)
 }
@@ -41,7 +46,11 @@ func primeFinder(
 			}
 
 			if isPrime(v) {
-				primeStream <- v
+				select {
+				case <-done:
+					return
+				case primeStream <- v:
+				}
 			}
 		}
 	}()
@@ -90,7 +99,7 @@ func TestFanOutFanInPipeline(_ *testing.T) {
 	//
 	// Now that we have four goroutines, we also have four channels, but our
 	// range over primes is only expecting one channel. This brings us to the
-	// fan-in portion of the pattern.
+	// fan-in portion of the pattern, see FanIn.
 	//
 	// Preserving Orders
 	//
@@ -99,51 +108,11 @@ func TestFanOutFanInPipeline(_ *testing.T) {
 	// We have done nothing to guarantee that the order in which items are read
 	// from the randIntStream is preserved as it makes its way through the
 	// prime sieve stage.
-	fanIn := func(
-		done <-chan interface{},
-		channels ...<-chan interface{},
-	) <-chan interface{} {
-		// In a nutshell, fanning in implementation involves creating the
-		// multiplexed(joined together) channel that consumers will read from,
-		// then spinning up one goroutine for each incoming channel, and one
-		// goroutine to close the multiplexed channel when the incoming
-		// channels have all been closed. Since we’re going to be creating a
-		// goroutine that is waiting on N other goroutines to complete, it
-		// makes sense to create a sync.WaitGroup to coordinate things. The
-		// multiplex function also notifies the WaitGroup that it’s done.
-
-		// Use wg to wait until all channels have been drained.
-		var wg sync.WaitGroup
-
-		multiplexedStream := make(chan interface{})
-		multiplex := func(c <-chan interface{}) {
-			defer wg.Done()
-			for i := range c {
-				select {
-				case <-done:
-					return
-				case multiplexedStream <- i:
-				}
-			}
-		}
-
-		// Select from all the channels.
-		wg.Add(len(channels))
-		for _, c := range channels {
-			go multiplex(c)
-		}
-
-		// Wait for all the reads to complete.
-		go func() {
-			wg.Wait()
-			close(multiplexedStream)
-		}()
-
-		return multiplexedStream
-	}
-
+	//
+	// See stage.OrderedMap for a fan-out fan-in which re-sequences results
+	// into input order, or FanOut with KeyHash which keeps order per key.
 	fmt.Println("Primes:")
-	for prime := range Take(done, fanIn(done, finders...), 10) {
+	for prime := range Take(done, FanIn(done, finders...), 10) {
 		fmt.Printf("\t%d\n", prime)
 	}
 	fmt.Printf("Search took: %v\n", time.Since(start))
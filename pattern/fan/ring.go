@@ -0,0 +1,63 @@
+package fan
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// hashRingReplicas is how many points each shard has on the ring. More
+// points spread the keys more evenly between shards.
+const hashRingReplicas = 100
+
+// hashRing is a consistent hash ring: each shard owns replicas points on a
+// circle of hashes, and a key belongs to the shard owning the first point at
+// or after the key's hash. Adding a shard only takes over the keys falling
+// right before its own points.
+type hashRing struct {
+	points []uint64 // sorted.
+	owners []int    // owners[i] is the shard owning points[i].
+}
+
+func newHashRing(shards, replicas int) *hashRing {
+	type point struct {
+		hash  uint64
+		owner int
+	}
+	all := make([]point, 0, shards*replicas)
+	for s := 0; s < shards; s++ {
+		for r := 0; r < replicas; r++ {
+			all = append(all, point{ringHash(strconv.Itoa(s) + "#" + strconv.Itoa(r)), s})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].hash < all[j].hash })
+
+	ring := &hashRing{points: make([]uint64, len(all)), owners: make([]int, len(all))}
+	for i, p := range all {
+		ring.points[i], ring.owners[i] = p.hash, p.owner
+	}
+	return ring
+}
+
+func (r *hashRing) shard(key string) int {
+	h := ringHash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0 // Wrap around the circle.
+	}
+	return r.owners[i]
+}
+
+// ringHash is FNV-1a followed by a finalizer mixing its bits, since FNV alone
+// places similar strings like "1#2" and "1#3" close on the ring.
+func ringHash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -1,5 +1,10 @@
 package orchan
 
+import (
+	"context"
+	"reflect"
+)
+
 // Or implement the Or-Channel Pattern.
 func Or(channels ...<-chan interface{}) <-chan interface{} {
 	// Recursion base case.
@@ -46,3 +51,99 @@ func Or(channels ...<-chan interface{}) <-chan interface{} {
 	}()
 	return orDone
 }
+
+// OrCtx is Or which also gives up when ctx is done. The returned channel is
+// closed when any of channels is closed or ctx is done, ctx.Err() tells which.
+//
+// Unlike wrapping Or in a select on ctx.Done(), ctx is passed down the
+// recursion tree, so canceling ctx tears down every goroutine of the tree
+// even if none of channels is ever closed.
+func OrCtx(ctx context.Context, channels ...<-chan interface{}) <-chan interface{} {
+	orDone := make(chan interface{})
+	go func() {
+		defer close(orDone)
+
+		switch len(channels) {
+		case 0:
+			<-ctx.Done()
+		case 1:
+			select {
+			case <-channels[0]:
+			case <-ctx.Done():
+			}
+		case 2:
+			select {
+			case <-channels[0]:
+			case <-channels[1]:
+			case <-ctx.Done():
+			}
+		default:
+			// Copy into a new slice: appending to channels[3:] could write
+			// into the caller's backing array.
+			rest := append(append([]<-chan interface{}{}, channels[3:]...), orDone)
+			select {
+			case <-channels[0]:
+			case <-channels[1]:
+			case <-channels[2]:
+			case <-ctx.Done():
+			case <-OrCtx(ctx, rest...):
+			}
+		}
+	}()
+	return orDone
+}
+
+// OrIndex is Or which also tells which channel fired: the returned channel
+// receives the index in channels of the first one to be closed (or to send),
+// then is closed.
+//
+// Rather than a tree of about n/2 goroutines, a single goroutine waits on all
+// of channels at once by reflect.Select, which can select over a number of
+// cases only known at runtime. See BenchmarkOr for how both compare.
+func OrIndex(channels ...<-chan interface{}) <-chan int {
+	if len(channels) == 0 {
+		return nil
+	}
+
+	cases := make([]reflect.SelectCase, len(channels))
+	for i, c := range channels {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c)}
+	}
+
+	// Buffered, so the goroutine can exit even if nobody reads the index.
+	index := make(chan int, 1)
+	go func() {
+		defer close(index)
+		chosen, _, _ := reflect.Select(cases)
+		index <- chosen
+	}()
+	return index
+}
+
+// And is the counterpart of Or: the returned channel is closed once every one
+// of channels is closed, e.g. once all of a set of goroutines have stopped.
+// Values sent on channels are discarded while waiting.
+//
+// The returned channel is also closed when done is closed, so a waiter which
+// gave up doesn't leave And's goroutine behind. Check done to tell which.
+//
+// Unlike Or, no recursion is needed: all channels have to be closed anyway,
+// so waiting for them one after another, in a single goroutine, takes no
+// longer than waiting for them all at once.
+func And(done <-chan interface{}, channels ...<-chan interface{}) <-chan interface{} {
+	andDone := make(chan interface{})
+	go func() {
+		defer close(andDone)
+		for _, c := range channels {
+			for closed := false; !closed; {
+				select {
+				case <-done:
+					return
+				case _, ok := <-c:
+					closed = !ok
+				}
+			}
+		}
+	}()
+	return andDone
+}
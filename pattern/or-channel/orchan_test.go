@@ -1,18 +1,25 @@
 package orchan
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"testing"
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 // TestOrChannel shows how to use Or func.
 func TestOrChannel(t *testing.T) {
 	sig := func(after time.Duration) <-chan interface{} {
+		// A timer rather than a sleeping goroutine, so the signals which never
+		// fire during the test don't leak.
 		c := make(chan interface{})
-		go func() {
-			defer close(c)
-			time.Sleep(after)
-		}()
+		time.AfterFunc(after, func() { close(c) })
 		return c
 	}
 
@@ -44,3 +51,117 @@ func TestOrChannel(t *testing.T) {
 	)
 	t.Logf("done after %v\n", time.Since(start))
 }
+
+// TestOrCtx shows OrCtx returning on ctx timeout before any channel closes.
+func TestOrCtx(t *testing.T) {
+	sig := func(after time.Duration) <-chan interface{} {
+		// A timer rather than a sleeping goroutine, so the signals which never
+		// fire during the test don't leak.
+		c := make(chan interface{})
+		time.AfterFunc(after, func() { close(c) })
+		return c
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	<-OrCtx(ctx, sig(1*time.Hour), sig(1*time.Minute), sig(1*time.Second), sig(2*time.Hour))
+	if err := ctx.Err(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ctx.Err() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	<-OrCtx(context.Background(), sig(1*time.Hour), sig(1*time.Millisecond))
+}
+
+func TestOrIndex(t *testing.T) {
+	channels := make([]<-chan interface{}, 5)
+	for i := range channels {
+		channels[i] = make(chan interface{})
+	}
+	fired := make(chan interface{})
+	close(fired)
+	channels[3] = fired
+
+	if i := <-OrIndex(channels...); i != 3 {
+		t.Errorf("got index %v, want 3", i)
+	}
+}
+
+// BenchmarkOr compares the recursive Or with the flat OrIndex, waiting on n
+// channels of which the last one is closed.
+//
+// run: go test -bench=Or -benchmem
+func BenchmarkOr(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		channels := func() ([]<-chan interface{}, chan interface{}) {
+			channels := make([]<-chan interface{}, n)
+			for i := range channels {
+				channels[i] = make(chan interface{})
+			}
+			last := make(chan interface{})
+			channels[n-1] = last
+			return channels, last
+		}
+
+		b.Run(fmt.Sprintf("Or/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				channels, last := channels()
+				orDone := Or(channels...)
+				close(last)
+				<-orDone
+			}
+		})
+		b.Run(fmt.Sprintf("OrIndex/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				channels, last := channels()
+				index := OrIndex(channels...)
+				close(last)
+				<-index
+			}
+		})
+	}
+}
+
+func TestAnd(t *testing.T) {
+	a, b, c := make(chan interface{}), make(chan interface{}), make(chan interface{})
+	andDone := And(nil, a, b, c)
+
+	close(b)
+	a <- "discarded"
+	close(a)
+	select {
+	case <-andDone:
+		t.Fatal("And closed before c")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(c)
+	<-andDone
+
+	// Abandoned: none of these is ever closed, done let And's goroutine go.
+	done := make(chan interface{})
+	andDone = And(done, make(chan interface{}), make(chan interface{}))
+	close(done)
+	<-andDone
+}
+
+// TestOrCtxKeepsCallerSlice shows OrCtx doesn't append into the spare
+// capacity of the caller's slice.
+func TestOrCtxKeepsCallerSlice(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	spare := make(chan interface{})
+	s := make([]<-chan interface{}, 5)
+	for i := range s {
+		s[i] = make(chan interface{})
+	}
+	s[4] = spare
+
+	orDone := OrCtx(ctx, s[:4]...)
+	cancel()
+	<-orDone
+	if s[4] != (<-chan interface{})(spare) {
+		t.Error("s[4] overwritten by OrCtx")
+	}
+}
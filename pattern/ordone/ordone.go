@@ -1,13 +1,18 @@
 package ordone
 
+import "context"
+
 // OrDone implement the or-done channel pattern. c is the channel which we don't
 // have control of.
 //
+// OrDone is generic over the element type of c, so typed streams stay typed
+// when wrapped. done stays a plain <-chan any since it only ever signals.
+//
 // See the Sleep stage in pipeline/stage.go for why we need ordone pattern.
 //
 // See tee/, bridge/ and stage/ for usage.
-func OrDone(done, c <-chan interface{}) <-chan interface{} {
-	valStream := make(chan interface{})
+func OrDone[T any](done <-chan any, c <-chan T) <-chan T {
+	valStream := make(chan T)
 	go func() {
 		defer close(valStream)
 
@@ -51,3 +56,28 @@ func OrDone(done, c <-chan interface{}) <-chan interface{} {
 	}()
 	return valStream
 }
+
+// OrDoneCtx is OrDone driven by a context.Context instead of a done channel.
+// valStream is closed when c is closed or ctx is done, in the latter case
+// ctx.Err() tells the reader why.
+func OrDoneCtx[T any](ctx context.Context, c <-chan T) <-chan T {
+	valStream := make(chan T)
+	go func() {
+		defer close(valStream)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-c:
+				if !ok {
+					return
+				}
+				select {
+				case valStream <- v:
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+	return valStream
+}
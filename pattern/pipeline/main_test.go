@@ -2,9 +2,14 @@ package main
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 // TestBatchProcessing shows how to use pipeline for batch processing: the stage
 // operate on chunks of data all at once instead of one discrete value at a
 // time, like a slice.
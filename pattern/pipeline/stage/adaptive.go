@@ -0,0 +1,137 @@
+package stage
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// AdaptiveBuffer stage is a Buffer whose capacity is not guessed up front, but
+// adjusted every interval within [min, max] from what it observes:
+//
+// * If items arrive faster than they are served, or an item arrived while the
+//   buffer was full, i.e. it blocked upstream, the capacity doubles. A buffer
+//   sitting full in front of a stalled consumer, with nothing arriving, does
+//   not grow.
+// * Otherwise it's sized after Little's law, L = λW: the arrival rate λ times
+//   the average time W items spent queued is the number of items the queue
+//   actually needs to hold. Twice that is kept for headroom, and the capacity
+//   shrinks at most by half per interval, so a short lull doesn't throw away
+//   a size a burst needed.
+//
+// size return the current capacity, so long-running pipelines can report what
+// queue length they settled on.
+func AdaptiveBuffer[T any](
+	done <-chan any,
+	in <-chan T,
+	min, max int,
+	interval time.Duration,
+) (_ <-chan T, size func() int) {
+	type queued struct {
+		v  T
+		at time.Time
+	}
+
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	capacity := int64(min)
+
+	out := make(chan T)
+	go func() {
+		defer close(out)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var queue []queued
+		var arrived, served int
+		var waited time.Duration // total time served items spent queued.
+		var full bool            // whether an arrival found the queue full this interval.
+
+		// held is an item which arrived while the queue was at capacity. It's
+		// how we know upstream actually pushed against the limit, rather than
+		// the queue merely sitting full in front of a stalled consumer. It
+		// joins the queue once there is room, until then in is not read.
+		var held queued
+		var holding bool
+
+		adjust := func() {
+			c := int(atomic.LoadInt64(&capacity))
+			next := c
+			if arrived > served || full {
+				next = c * 2
+			} else {
+				var l float64
+				if served > 0 {
+					lambda := float64(arrived) / interval.Seconds()
+					w := waited.Seconds() / float64(served)
+					l = lambda * w
+				}
+				next = int(math.Ceil(2 * l))
+				if next < c/2 {
+					next = c / 2
+				}
+			}
+			if next < min {
+				next = min
+			}
+			if next > max {
+				next = max
+			}
+			atomic.StoreInt64(&capacity, int64(next))
+			arrived, served, waited, full = 0, 0, 0, false
+		}
+
+		for {
+			// Toggle the cases by setting the channels to nil: only receive
+			// when there is room, only send when there is something.
+			c := int(atomic.LoadInt64(&capacity))
+			if holding && len(queue) < c {
+				queue = append(queue, held)
+				held, holding = queued{}, false
+			}
+			recv := in
+			if holding {
+				recv = nil
+			}
+			var send chan<- T
+			var head T
+			if len(queue) > 0 {
+				send = out
+				head = queue[0].v
+			} else if in == nil {
+				return // in is closed and everything is delivered.
+			}
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				adjust()
+			case v, ok := <-recv:
+				if !ok {
+					in = nil
+					continue
+				}
+				arrived++
+				if len(queue) >= c {
+					full = true
+					held, holding = queued{v, time.Now()}, true
+					continue
+				}
+				queue = append(queue, queued{v, time.Now()})
+			case send <- head:
+				waited += time.Since(queue[0].at)
+				queue[0] = queued{} // Let it be garbage collected.
+				queue = queue[1:]
+				served++
+			}
+		}
+	}()
+
+	return out, func() int { return int(atomic.LoadInt64(&capacity)) }
+}
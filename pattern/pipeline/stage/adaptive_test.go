@@ -0,0 +1,60 @@
+package stage
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAdaptiveBuffer shows the buffer growing while a burst backs up against a
+// slow consumer, then shrinking back once things are quiet.
+func TestAdaptiveBuffer(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	in := make(chan int)
+	out, size := AdaptiveBuffer(done, in, 1, 32, 5*time.Millisecond)
+
+	go func() {
+		defer close(in)
+		for i := 0; i < 200; i++ {
+			in <- i
+		}
+		time.Sleep(100 * time.Millisecond) // quiet
+	}()
+
+	peak := 0
+	i := 0
+	for v := range out {
+		if v != i {
+			t.Fatalf("got %d, want %d", v, i)
+		}
+		i++
+		if s := size(); s > peak {
+			peak = s
+		}
+		time.Sleep(200 * time.Microsecond) // the slow consumer
+	}
+	if peak < 8 {
+		t.Errorf("peak size %d, want it to have grown", peak)
+	}
+	if s := size(); s >= peak {
+		t.Errorf("final size %d, want it to have shrunk from %d", s, peak)
+	}
+}
+
+// TestAdaptiveBufferIdleProducer shows a full buffer not growing when nothing
+// tries to get in: only pressure from upstream makes it grow.
+func TestAdaptiveBufferIdleProducer(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	in := make(chan int)
+	_, size := AdaptiveBuffer(done, in, 2, 32, time.Millisecond)
+	in <- 1
+	in <- 2 // Full now, and nobody reads out.
+
+	time.Sleep(20 * time.Millisecond)
+	if s := size(); s != 2 {
+		t.Errorf("size %d, want it to stay 2 without pressure", s)
+	}
+}
@@ -0,0 +1,218 @@
+package stage
+
+import (
+	"time"
+)
+
+// minWindow is what window durations <= 0 are raised to, since a ticker
+// can't tick every 0.
+const minWindow = time.Millisecond
+
+// Batch stage chunk items from in into slices of up to n items. A batch is
+// sent when it's full, or when maxLatency has elapsed since its first item
+// arrived, whichever comes first. So a trickling stream is still flushed in
+// time, while a busy stream gets the full chunks.
+//
+// It's the pipeline counterpart of what bufio does in queue/: trading latency
+// for fewer, bulkier writes downstream. The last partial batch is sent when in
+// is closed. n below 1 is taken as 1.
+func Batch[T any](
+	done <-chan any,
+	in <-chan T,
+	n int,
+	maxLatency time.Duration,
+) <-chan []T {
+	if n < 1 {
+		n = 1
+	}
+
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+
+		var batch []T
+		var timer *time.Timer
+		var expire <-chan time.Time // nil until a batch is started.
+
+		// flush send the pending batch and reset, return false if done.
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, expire = nil, nil
+			}
+			if len(batch) == 0 {
+				return true
+			}
+			select {
+			case <-done:
+				return false
+			case out <- batch:
+			}
+			batch = nil
+			return true
+		}
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-expire:
+				timer, expire = nil, nil
+				if !flush() {
+					return
+				}
+			case v, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				if len(batch) == 0 {
+					batch = make([]T, 0, n)
+					timer = time.NewTimer(maxLatency)
+					expire = timer.C
+				}
+				batch = append(batch, v)
+				if len(batch) >= n && !flush() {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// TumblingWindow stage group items from in into consecutive, non-overlapping
+// windows of duration d, and send each window's items at the end of the
+// window. Empty windows are not sent. The last window is sent when in is
+// closed.
+func TumblingWindow[T any](done <-chan any, in <-chan T, d time.Duration) <-chan []T {
+	if d < minWindow {
+		d = minWindow
+	}
+
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+
+		ticker := time.NewTicker(d)
+		defer ticker.Stop()
+
+		var window []T
+		send := func() bool {
+			if len(window) == 0 {
+				return true
+			}
+			select {
+			case <-done:
+				return false
+			case out <- window:
+			}
+			window = nil
+			return true
+		}
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if !send() {
+					return
+				}
+			case v, ok := <-in:
+				if !ok {
+					send()
+					return
+				}
+				window = append(window, v)
+			}
+		}
+	}()
+	return out
+}
+
+// SlidingWindow stage send, every step, the items from in which arrived
+// within the last size duration. Windows overlap when step < size, so an item
+// is sent in roughly size/step windows. Empty windows are not sent. When in is
+// closed, the current window is sent before closing.
+//
+// Each window is a fresh slice, it's safe for the consumer to keep or modify
+// it.
+func SlidingWindow[T any](
+	done <-chan any,
+	in <-chan T,
+	size time.Duration,
+	step time.Duration,
+) <-chan []T {
+	type stamped struct {
+		at time.Time
+		v  T
+	}
+
+	if size < minWindow {
+		size = minWindow
+	}
+	if step < minWindow {
+		step = minWindow
+	}
+
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+
+		ticker := time.NewTicker(step)
+		defer ticker.Stop()
+
+		var items []stamped
+		// send the window ending at now, if not empty. It returns false if
+		// done was closed meanwhile.
+		send := func(now time.Time) bool {
+			// Evict items that slid out of the window.
+			start := now.Add(-size)
+			i := 0
+			for i < len(items) && !items[i].at.After(start) {
+				i++
+			}
+			items = items[i:]
+			if len(items) == 0 {
+				return true
+			}
+
+			window := make([]T, len(items))
+			for i, s := range items {
+				window[i] = s.v
+			}
+			select {
+			case <-done:
+				return false
+			case out <- window:
+				return true
+			}
+		}
+
+		for {
+			select {
+			case <-done:
+				return
+			case v, ok := <-in:
+				if !ok {
+					// Flush, like Batch and TumblingWindow, so the items
+					// since the last tick are not lost.
+					send(time.Now())
+					return
+				}
+				items = append(items, stamped{time.Now(), v})
+			case now := <-ticker.C:
+				if !send(now) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
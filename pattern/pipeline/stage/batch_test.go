@@ -0,0 +1,105 @@
+package stage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestBatchBySize shows a busy stream is chunked by size, with the remainder
+// flushed on close.
+func TestBatchBySize(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	var got [][]int
+	for b := range Batch(done, Take(done, Repeat(done, 1, 2, 3, 4, 5, 6, 7), 7), 3, time.Hour) {
+		got = append(got, b)
+	}
+	if fmt.Sprint(got) != "[[1 2 3] [4 5 6] [7]]" {
+		t.Errorf("got %v, want [[1 2 3] [4 5 6] [7]]", got)
+	}
+}
+
+// TestBatchByLatency shows a trickling stream is flushed after maxLatency
+// rather than waiting for a full batch.
+func TestBatchByLatency(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	slow := Sleep(done, Take(done, Repeat(done, 1, 2, 3), 3), 30*time.Millisecond)
+	var got [][]int
+	for b := range Batch(done, slow, 100, 10*time.Millisecond) {
+		got = append(got, b)
+	}
+	if fmt.Sprint(got) != "[[1] [2] [3]]" {
+		t.Errorf("got %v, want [[1] [2] [3]]", got)
+	}
+}
+
+func TestTumblingWindow(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	n := 0
+	for w := range TumblingWindow(done, Take(done, Repeat(done, 1), 5), time.Hour) {
+		n += len(w)
+	}
+	if n != 5 {
+		t.Errorf("got %d items, want 5", n)
+	}
+}
+
+// TestSlidingWindow shows windows overlapping: with step a fifth of size, an
+// item appears in several consecutive windows.
+func TestSlidingWindow(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	in := make(chan int)
+	out := SlidingWindow(done, in, 100*time.Millisecond, 20*time.Millisecond)
+	in <- 1
+
+	seen := 0
+	for w := range out {
+		if len(w) != 1 || w[0] != 1 {
+			t.Fatalf("got window %v, want [1]", w)
+		}
+		seen++
+		if seen == 3 {
+			break
+		}
+	}
+	close(in)
+}
+
+// TestSlidingWindowFlush shows the items since the last tick are sent when in
+// closes, even though no tick ever came.
+func TestSlidingWindowFlush(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	n := 0
+	for w := range SlidingWindow(done, Take(done, Repeat(done, 1), 5), time.Hour, time.Hour) {
+		n += len(w)
+	}
+	if n != 5 {
+		t.Errorf("got %d items, want 5", n)
+	}
+}
+
+// TestWindowBadSizes shows bad sizes and durations clamped rather than
+// panicking.
+func TestWindowBadSizes(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	for _, out := range []<-chan []int{
+		Batch(done, Take(done, Repeat(done, 1), 3), -1, time.Hour),
+		TumblingWindow(done, Take(done, Repeat(done, 1), 3), 0),
+		SlidingWindow(done, Take(done, Repeat(done, 1), 3), -time.Second, 0),
+	} {
+		for range out {
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package stage
+
+import (
+	"sync"
+)
+
+// Zip stage pair items up by position: the ith item sent is a slice holding
+// the ith item of every input, in inputs order. It closes as soon as any
+// input closes, since no complete tuple can be made anymore.
+//
+// Unlike fanIn in fan/, which merge in whatever order items arrive, Zip reads
+// the inputs in turn, in a single goroutine, so nothing is left blocked when
+// one input closes early: the others are simply not read anymore, and left
+// to done as usual.
+func Zip[T any](done <-chan any, inputs ...<-chan T) <-chan []T {
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+		if len(inputs) == 0 {
+			return
+		}
+		for {
+			tuple := make([]T, len(inputs))
+			for i, in := range inputs {
+				select {
+				case <-done:
+					return
+				case v, ok := <-in:
+					if !ok {
+						return
+					}
+					tuple[i] = v
+				}
+			}
+			select {
+			case <-done:
+				return
+			case out <- tuple:
+			}
+		}
+	}()
+	return out
+}
+
+// CombineLatest stage send, whenever any input sends an item, the latest item
+// of every input, in inputs order. Nothing is sent until every input has sent
+// at least once. It closes when all inputs are closed, or when one closes
+// without ever sending, since nothing could be combined anymore.
+//
+// Every slice sent is a fresh copy.
+func CombineLatest[T any](done <-chan any, inputs ...<-chan T) <-chan []T {
+	type update struct {
+		i      int
+		v      T
+		closed bool
+	}
+
+	out := make(chan []T)
+
+	// quit let the readers below exit when CombineLatest gives up early,
+	// even though done is not closed.
+	quit := make(chan any)
+	updates := make(chan update)
+	var wg sync.WaitGroup
+	wg.Add(len(inputs))
+	for i, in := range inputs {
+		go func(i int, in <-chan T) {
+			defer wg.Done()
+			for {
+				var u update
+				select {
+				case <-done:
+					return
+				case <-quit:
+					return
+				case v, ok := <-in:
+					u = update{i: i, v: v, closed: !ok}
+				}
+				select {
+				case <-done:
+					return
+				case <-quit:
+					return
+				case updates <- u:
+				}
+				if u.closed {
+					return
+				}
+			}
+		}(i, in)
+	}
+
+	go func() {
+		defer close(out)
+		defer wg.Wait() // Leave no reader behind.
+		defer close(quit)
+
+		latest := make([]T, len(inputs))
+		has := make([]bool, len(inputs))
+		missing := len(inputs) // inputs yet to send their first item.
+		open := len(inputs)
+		for open > 0 {
+			var u update
+			select {
+			case <-done:
+				return
+			case u = <-updates:
+			}
+
+			if u.closed {
+				if !has[u.i] {
+					return
+				}
+				open--
+				continue
+			}
+
+			if !has[u.i] {
+				has[u.i] = true
+				missing--
+			}
+			latest[u.i] = u.v
+			if missing > 0 {
+				continue
+			}
+
+			combined := make([]T, len(latest))
+			copy(combined, latest)
+			select {
+			case <-done:
+				return
+			case out <- combined:
+			}
+		}
+	}()
+	return out
+}
+
+// Merge stage merge inputs which are each sorted according to less into one
+// sorted stream, a k-way merge. It closes when all inputs are closed.
+//
+// Merge has to see the next item of every open input before it can tell
+// which is the smallest, so an input which is slow to send holds up the
+// whole stream.
+func Merge[T any](done <-chan any, less func(a, b T) bool, inputs ...<-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+
+		heads := make([]T, len(inputs))
+		open := make([]bool, len(inputs))
+
+		// next read the next item of input i into heads[i], return false
+		// if done.
+		next := func(i int) bool {
+			select {
+			case <-done:
+				return false
+			case v, ok := <-inputs[i]:
+				heads[i], open[i] = v, ok
+				return true
+			}
+		}
+
+		for i := range inputs {
+			if !next(i) {
+				return
+			}
+		}
+		for {
+			// A linear scan is fine for the handful of inputs Merge is
+			// meant for.
+			first := -1
+			for i := range heads {
+				if open[i] && (first < 0 || less(heads[i], heads[first])) {
+					first = i
+				}
+			}
+			if first < 0 {
+				return
+			}
+
+			select {
+			case <-done:
+				return
+			case out <- heads[first]:
+			}
+			if !next(first) {
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,78 @@
+package stage
+
+import (
+	"fmt"
+	"learn/go/concurrency/internal/leaktest"
+	"testing"
+)
+
+func TestZip(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	var got [][]int
+	for tuple := range Zip(done, slice(done, 1, 2, 3), Repeat(done, 10), slice(done, 100, 200)) {
+		got = append(got, tuple)
+	}
+	// The third input closing early ends the zip.
+	if fmt.Sprint(got) != "[[1 10 100] [2 10 200]]" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestCombineLatest(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	a, b := make(chan string), make(chan string)
+	out := CombineLatest(done, a, b)
+
+	// Each send below only happens once the previous update is combined, so
+	// the updates can't race each other through CombineLatest.
+	a <- "a1"
+	b <- "b1"
+	if got := <-out; fmt.Sprint(got) != "[a1 b1]" {
+		t.Errorf("got %v, want [a1 b1]", got)
+	}
+	b <- "b2"
+	if got := <-out; fmt.Sprint(got) != "[a1 b2]" {
+		t.Errorf("got %v, want [a1 b2]", got)
+	}
+	a <- "a2"
+	if got := <-out; fmt.Sprint(got) != "[a2 b2]" {
+		t.Errorf("got %v, want [a2 b2]", got)
+	}
+	close(a)
+	b <- "b3"
+	if got := <-out; fmt.Sprint(got) != "[a2 b3]" {
+		t.Errorf("got %v, want [a2 b3]", got)
+	}
+	close(b)
+	if _, ok := <-out; ok {
+		t.Errorf("want out closed once all inputs are")
+	}
+}
+
+// TestCombineLatestNoLeak shows an input closing before sending anything ends
+// CombineLatest, without leaving the goroutine reading the other input behind
+// even though done is still open.
+func TestCombineLatestNoLeak(t *testing.T) {
+	leaktest.Check(t)
+
+	done := make(chan interface{}) // Never closed, to not hide the leak.
+	empty := make(chan int)
+	close(empty)
+	for range CombineLatest(done, make(chan int), empty) {
+	}
+}
+
+func TestMerge(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	less := func(a, b int) bool { return a < b }
+	got := collect(Merge(done, less, slice(done, 1, 4, 9), slice(done, 2, 3, 10, 11), slice[int](done)))
+	if fmt.Sprint(got) != "[1 2 3 4 9 10 11]" {
+		t.Errorf("got %v", got)
+	}
+}
@@ -0,0 +1,266 @@
+package stage
+
+import (
+	"context"
+	. "learn/go/concurrency/pattern/ordone"
+	"time"
+)
+
+// The *Ctx stages below are the context.Context flavors of the done-channel
+// stages, so a pipeline can be wired straight into request handling (see
+// context/smartlogic) without an adapter goroutine turning ctx.Done() into a
+// done channel.
+//
+// They stop on ctx.Done() and close their output. A downstream consumer sees
+// the output closed, and ctx.Err() tells it whether that's because the stream
+// ended (nil) or because it was canceled or timed out:
+//
+//		for v := range TakeCtx(ctx, RepeatCtx(ctx, 1), 10) {
+//			...
+//		}
+//		if err := ctx.Err(); err != nil {
+//			return err
+//		}
+//
+// Every stage of stage.go and transform.go has one, except on purpose:
+//
+// * Sleep and SleepOrDone only differ in how they watch done, SleepCtx stands
+//   for both.
+// * Sleep1 is there to show a broken stage, not to be used.
+// * The *Any wrappers only exist for interface{} streams, ctx stages are
+//   generic from the start.
+
+// RepeatCtx is Repeat driven by ctx.
+func RepeatCtx[T any](ctx context.Context, values ...T) <-chan T {
+	valueStream := make(chan T)
+	go func() {
+		defer close(valueStream)
+		for {
+			for _, v := range values {
+				select {
+				case <-ctx.Done():
+					return
+				case valueStream <- v:
+				}
+			}
+		}
+	}()
+	return valueStream
+}
+
+// TakeCtx is Take driven by ctx.
+func TakeCtx[T any](ctx context.Context, valueStream <-chan T, num int) <-chan T {
+	takeStream := make(chan T)
+	go func() {
+		defer close(takeStream)
+		for i := 0; i < num; i++ {
+			var v T
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok = <-valueStream:
+				if !ok {
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case takeStream <- v:
+			}
+		}
+	}()
+	return takeStream
+}
+
+// RepeatFnCtx is RepeatFn driven by ctx.
+func RepeatFnCtx[T any](ctx context.Context, fn func() T) <-chan T {
+	valueStream := make(chan T)
+	go func() {
+		defer close(valueStream)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case valueStream <- fn():
+			}
+		}
+	}()
+	return valueStream
+}
+
+// ToStringCtx is ToString driven by ctx.
+func ToStringCtx(ctx context.Context, valueStream <-chan interface{}) <-chan string {
+	stringStream := make(chan string)
+	go func() {
+		defer close(stringStream)
+		for v := range OrDoneCtx(ctx, valueStream) {
+			select {
+			case <-ctx.Done():
+				return
+			case stringStream <- v.(string):
+			}
+		}
+	}()
+	return stringStream
+}
+
+// ToIntCtx is ToInt driven by ctx.
+func ToIntCtx(ctx context.Context, valueStream <-chan interface{}) <-chan int {
+	intStream := make(chan int)
+	go func() {
+		defer close(intStream)
+		for v := range OrDoneCtx(ctx, valueStream) {
+			select {
+			case <-ctx.Done():
+				return
+			case intStream <- v.(int):
+			}
+		}
+	}()
+	return intStream
+}
+
+// SleepCtx is Sleep and SleepOrDone driven by ctx. Unlike SleepOrDone, the sleep itself
+// is also cut short by ctx.
+func SleepCtx[T any](ctx context.Context, in <-chan T, d time.Duration) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for v := range OrDoneCtx(ctx, in) {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- v:
+			}
+
+			timer := time.NewTimer(d)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+	return out
+}
+
+// BufferCtx is Buffer driven by ctx.
+func BufferCtx[T any](ctx context.Context, in <-chan T, n int) <-chan T {
+	out := make(chan T, n)
+	go func() {
+		defer close(out)
+		for v := range OrDoneCtx(ctx, in) {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- v:
+			}
+		}
+	}()
+	return out
+}
+
+// MapCtx is Map driven by ctx.
+func MapCtx[T, U any](ctx context.Context, in <-chan T, fn func(T) U) <-chan U {
+	out := make(chan U)
+	go func() {
+		defer close(out)
+		for v := range OrDoneCtx(ctx, in) {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- fn(v):
+			}
+		}
+	}()
+	return out
+}
+
+// FilterCtx is Filter driven by ctx.
+func FilterCtx[T any](ctx context.Context, in <-chan T, keep func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for v := range OrDoneCtx(ctx, in) {
+			if !keep(v) {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- v:
+			}
+		}
+	}()
+	return out
+}
+
+// FlatMapCtx is FlatMap driven by ctx.
+func FlatMapCtx[T, U any](ctx context.Context, in <-chan T, fn func(T) []U) <-chan U {
+	out := make(chan U)
+	go func() {
+		defer close(out)
+		for v := range OrDoneCtx(ctx, in) {
+			for _, u := range fn(v) {
+				select {
+				case <-ctx.Done():
+					return
+				case out <- u:
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// ScanCtx is Scan driven by ctx.
+func ScanCtx[T, A any](
+	ctx context.Context,
+	in <-chan T,
+	init A,
+	fn func(acc A, v T) A,
+) <-chan A {
+	out := make(chan A)
+	go func() {
+		defer close(out)
+		acc := init
+		for v := range OrDoneCtx(ctx, in) {
+			acc = fn(acc, v)
+			select {
+			case <-ctx.Done():
+				return
+			case out <- acc:
+			}
+		}
+	}()
+	return out
+}
+
+// ReduceCtx is Reduce driven by ctx. If ctx is done before in is closed,
+// nothing is sent and ctx.Err() tells why.
+func ReduceCtx[T, A any](
+	ctx context.Context,
+	in <-chan T,
+	init A,
+	fn func(acc A, v T) A,
+) <-chan A {
+	out := make(chan A)
+	go func() {
+		defer close(out)
+		acc := init
+		for v := range OrDoneCtx(ctx, in) {
+			acc = fn(acc, v)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+		case out <- acc:
+		}
+	}()
+	return out
+}
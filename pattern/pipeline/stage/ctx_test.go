@@ -0,0 +1,76 @@
+package stage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTakeFromRepeatCtx(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got := collect(TakeCtx(ctx, MapCtx(ctx, RepeatCtx(ctx, 1, 2), func(v int) int { return v * 10 }), 4))
+	if fmt.Sprint(got) != "[10 20 10 20]" {
+		t.Errorf("got %v, want [10 20 10 20]", got)
+	}
+	if err := ctx.Err(); err != nil {
+		t.Errorf("ctx.Err() = %v, want nil", err)
+	}
+}
+
+// TestCtxDeadline shows a deadline cutting a slow pipeline short, and the
+// consumer learning why through ctx.Err().
+func TestCtxDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	n := 0
+	for range SleepCtx(ctx, RepeatCtx(ctx, 0), 20*time.Millisecond) {
+		n++
+	}
+	if n == 0 || n > 4 {
+		t.Errorf("got %v items, want 1 to 4", n)
+	}
+	if err := ctx.Err(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ctx.Err() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestReduceCtx(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sum := func(acc, v int) int { return acc + v }
+
+	if total := <-ReduceCtx(ctx, TakeCtx(ctx, RepeatCtx(ctx, 1, 2, 3), 3), 0, sum); total != 6 {
+		t.Errorf("got %v, want 6", total)
+	}
+
+	out := ReduceCtx(ctx, RepeatCtx(ctx, 1), 0, sum)
+	cancel()
+	if v, ok := <-out; ok {
+		t.Errorf("got %v, want out closed without value", v)
+	}
+}
+
+func TestToIntToStringCtx(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sum := 0
+	for v := range ToIntCtx(ctx, TakeCtx(ctx, RepeatCtx[interface{}](ctx, 1, 2), 4)) {
+		sum += v
+	}
+	if sum != 6 {
+		t.Errorf("sum = %v, want 6", sum)
+	}
+
+	var got string
+	for v := range ToStringCtx(ctx, TakeCtx(ctx, RepeatCtx[interface{}](ctx, "a", "b"), 3)) {
+		got += v
+	}
+	if got != "aba" {
+		t.Errorf("got %q, want aba", got)
+	}
+}
@@ -0,0 +1,151 @@
+package stage
+
+import (
+	"container/list"
+	"hash/fnv"
+	"math"
+	. "learn/go/concurrency/pattern/ordone"
+)
+
+// Distinct stage drop items whose key, as given by key, has been seen among
+// the last window distinct keys. Keys are tracked exactly in an LRU, so memory
+// is bounded by window, and a key seen again is refreshed: a duplicate is only
+// let through once window other keys came by since its last occurrence.
+func Distinct[T any, K comparable](
+	done <-chan any,
+	in <-chan T,
+	key func(T) K,
+	window int,
+) <-chan T {
+	if window < 1 {
+		window = 1
+	}
+
+	out := make(chan T)
+	go func() {
+		defer close(out)
+
+		recent := list.New() // front is the most recent.
+		seen := make(map[K]*list.Element, window)
+		for v := range OrDone(done, in) {
+			k := key(v)
+			if e, ok := seen[k]; ok {
+				recent.MoveToFront(e)
+				continue
+			}
+
+			seen[k] = recent.PushFront(k)
+			if recent.Len() > window {
+				oldest := recent.Back()
+				recent.Remove(oldest)
+				delete(seen, oldest.Value.(K))
+			}
+
+			select {
+			case <-done:
+				return
+			case out <- v:
+			}
+		}
+	}()
+	return out
+}
+
+// DistinctBloom stage is Distinct for streams with too many keys to keep: it
+// remembers keys in bloom filters, sized for expected keys with a false
+// positive rate of fpRate. So it may drop an item which is not a duplicate,
+// at about fpRate, but never let a duplicate through within its window.
+//
+// A bloom filter can't forget, and fills up. So two are used in turn: once
+// the current one holds expected keys, it becomes the previous one and a
+// fresh one takes its place. This bounds memory to two filters, and make the
+// window at least the last expected keys.
+func DistinctBloom[T any](
+	done <-chan any,
+	in <-chan T,
+	key func(T) string,
+	expected int,
+	fpRate float64,
+) <-chan T {
+	if expected < 1 {
+		expected = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+
+	out := make(chan T)
+	go func() {
+		defer close(out)
+
+		current := newBloom(expected, fpRate)
+		var previous *bloom
+		for v := range OrDone(done, in) {
+			k := key(v)
+			if current.has(k) || (previous != nil && previous.has(k)) {
+				continue
+			}
+
+			if current.n >= expected {
+				previous, current = current, newBloom(expected, fpRate)
+			}
+			current.add(k)
+
+			select {
+			case <-done:
+				return
+			case out <- v:
+			}
+		}
+	}()
+	return out
+}
+
+// bloom is a bloom filter with m bits and k hash functions, derived from one
+// 64-bit FNV-1a hash by double hashing.
+type bloom struct {
+	bits []uint64
+	m    uint64
+	k    int
+	n    int // keys added.
+}
+
+func newBloom(n int, p float64) *bloom {
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &bloom{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+func (b *bloom) locations(key string) func(i int) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := sum&0xffffffff, sum>>32|1
+	return func(i int) uint64 { return (h1 + uint64(i)*h2) % b.m }
+}
+
+func (b *bloom) add(key string) {
+	loc := b.locations(key)
+	for i := 0; i < b.k; i++ {
+		l := loc(i)
+		b.bits[l/64] |= 1 << (l % 64)
+	}
+	b.n++
+}
+
+func (b *bloom) has(key string) bool {
+	loc := b.locations(key)
+	for i := 0; i < b.k; i++ {
+		l := loc(i)
+		if b.bits[l/64]&(1<<(l%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
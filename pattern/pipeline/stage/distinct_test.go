@@ -0,0 +1,46 @@
+package stage
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestDistinct(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	self := func(v int) int { return v }
+	got := collect(Distinct(done, Take(done, Repeat(done, 1, 2, 1, 3, 2, 4, 1), 7), self, 2))
+	// With a window of 2: the second 1 is a duplicate, and refreshed. The
+	// second 2 is not, since 1 and 3 pushed it out of the window.
+	if fmt.Sprint(got) != "[1 2 3 2 4 1]" {
+		t.Errorf("got %v, want [1 2 3 2 4 1]", got)
+	}
+}
+
+// TestDistinctBloom shows a bloom filter deduplicating the output of RepeatFn,
+// dropping few unique items by mistake.
+func TestDistinctBloom(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	const unique = 10000
+	r := rand.New(rand.NewSource(1))
+	ids := RepeatFn(done, func() int { return r.Intn(unique) })
+
+	seen := make(map[int]bool)
+	for v := range DistinctBloom(done, Take(done, ids, 5*unique), strconv.Itoa, unique, 0.01) {
+		if seen[v] {
+			t.Fatalf("duplicate %v let through", v)
+		}
+		seen[v] = true
+	}
+
+	// Take 5*unique draws cover nearly all the unique values, of which about
+	// 1% should be lost to false positives.
+	if len(seen) < unique*95/100 {
+		t.Errorf("got %d distinct items, want about %d", len(seen), unique*99/100)
+	}
+}
@@ -0,0 +1,147 @@
+package stage
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics record what a stage is doing, to tell which stage is the
+// bottleneck, hence where a Buffer helps (see queue/):
+//
+// * A starved stage spend its time blocked receiving: upstream is slower.
+// * A backpressured stage spend its time blocked sending: downstream is slower.
+// * The bottleneck is the stage blocked on neither, with a full queue in
+//   front of it.
+//
+// Metrics is an expvar.Var, so it can be published as is:
+//
+//		expvar.Publish("ingest.parse", m)
+type Metrics struct {
+	// Accessed atomically, kept first for alignment on 32-bit platforms.
+	itemsIn  int64
+	itemsOut int64
+	recvWait int64 // nanoseconds
+	sendWait int64 // nanoseconds
+	queueMax int64
+
+	name    string
+	started time.Time
+	queue   atomic.Value // func() (length, capacity int), set by Meter.
+}
+
+// MetricsSnapshot is a point-in-time copy of Metrics.
+type MetricsSnapshot struct {
+	Name       string
+	ItemsIn    int64         // Items the stage received.
+	ItemsOut   int64         // Items the stage sent.
+	Throughput float64       // ItemsOut per second since the stage started.
+	RecvWait   time.Duration // Time blocked waiting for upstream.
+	SendWait   time.Duration // Time blocked waiting for downstream.
+	QueueLen   int           // Items buffered in the stage's output now.
+	QueueCap   int           // Capacity of the stage's output.
+	QueueMax   int           // Most items seen buffered in the stage's output.
+}
+
+// NewMetrics create Metrics for the stage called name.
+func NewMetrics(name string) *Metrics {
+	return &Metrics{name: name, started: time.Now()}
+}
+
+// Snapshot return the current numbers.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	s := MetricsSnapshot{
+		Name:     m.name,
+		ItemsIn:  atomic.LoadInt64(&m.itemsIn),
+		ItemsOut: atomic.LoadInt64(&m.itemsOut),
+		RecvWait: time.Duration(atomic.LoadInt64(&m.recvWait)),
+		SendWait: time.Duration(atomic.LoadInt64(&m.sendWait)),
+		QueueMax: int(atomic.LoadInt64(&m.queueMax)),
+	}
+	if elapsed := time.Since(m.started).Seconds(); elapsed > 0 {
+		s.Throughput = float64(s.ItemsOut) / elapsed
+	}
+	if queue, ok := m.queue.Load().(func() (int, int)); ok {
+		s.QueueLen, s.QueueCap = queue()
+	}
+	return s
+}
+
+// String return the snapshot as JSON, which makes Metrics an expvar.Var.
+func (m *Metrics) String() string {
+	b, _ := json.Marshal(m.Snapshot())
+	return string(b)
+}
+
+var _ expvar.Var = (*Metrics)(nil)
+
+// Meter wrap stage so it records into m. It does so by relaying the input
+// and the output of stage, which cost two goroutines and one extra item in
+// flight on each side.
+func Meter[T, U any](
+	m *Metrics,
+	stage func(done <-chan any, in <-chan T) <-chan U,
+) func(done <-chan any, in <-chan T) <-chan U {
+	return func(done <-chan any, in <-chan T) <-chan U {
+		stageIn := make(chan T)
+		go func() {
+			defer close(stageIn)
+			for {
+				start := time.Now()
+				var v T
+				var ok bool
+				select {
+				case <-done:
+					return
+				case v, ok = <-in:
+				}
+				atomic.AddInt64(&m.recvWait, int64(time.Since(start)))
+				if !ok {
+					return
+				}
+				select {
+				case <-done:
+					return
+				case stageIn <- v:
+					atomic.AddInt64(&m.itemsIn, 1)
+				}
+			}
+		}()
+
+		stageOut := stage(done, stageIn)
+		m.queue.Store(func() (int, int) { return len(stageOut), cap(stageOut) })
+
+		out := make(chan U)
+		go func() {
+			defer close(out)
+			for {
+				// Sample how full the stage's buffer is, if it has one.
+				if n := int64(len(stageOut)); n > atomic.LoadInt64(&m.queueMax) {
+					atomic.StoreInt64(&m.queueMax, n)
+				}
+
+				var v U
+				var ok bool
+				select {
+				case <-done:
+					return
+				case v, ok = <-stageOut:
+				}
+				if !ok {
+					return
+				}
+
+				start := time.Now()
+				select {
+				case <-done:
+					return
+				case out <- v:
+					atomic.AddInt64(&m.itemsOut, 1)
+				}
+				atomic.AddInt64(&m.sendWait, int64(time.Since(start)))
+			}
+		}()
+		return out
+	}
+}
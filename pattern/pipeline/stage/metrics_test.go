@@ -0,0 +1,51 @@
+package stage
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestMetricsBottleneck rebuild the pipeline of queue/ with a fast and a slow
+// stage, and shows the metrics pointing at the slow one.
+func TestMetricsBottleneck(t *testing.T) {
+	sleep := func(d time.Duration) Stage[int] {
+		return func(done <-chan any, in <-chan int) <-chan int { return Sleep(done, in, d) }
+	}
+
+	p := NewPipeline(func(done <-chan any) <-chan int {
+		return Take(done, Repeat(done, 0), 10)
+	}).
+		Then("short", sleep(1*time.Millisecond)).
+		Then("buffer", buffer(5)).
+		Then("long", sleep(10*time.Millisecond)).
+		Metered().
+		Start(nil)
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	m := p.Metrics()
+	if len(m) != 3 {
+		t.Fatalf("got %d metrics, want 3", len(m))
+	}
+	buf, long := m[1], m[2]
+	for _, s := range m {
+		if s.ItemsIn != 10 || s.ItemsOut != 10 {
+			t.Errorf("%s: in %d, out %d, want 10 and 10", s.Name, s.ItemsIn, s.ItemsOut)
+		}
+	}
+	// The stage in front of the slow one is held up by it, while the slow one
+	// always has an item ready thanks to the buffer.
+	if buf.SendWait < long.RecvWait {
+		t.Errorf("buffer SendWait %v < long RecvWait %v", buf.SendWait, long.RecvWait)
+	}
+	if buf.QueueCap != 5 || buf.QueueMax == 0 {
+		t.Errorf("buffer: cap %d, max %d, want cap 5 and some items queued", buf.QueueCap, buf.QueueMax)
+	}
+
+	var exported []MetricsSnapshot
+	if err := json.Unmarshal([]byte(p.Var().String()), &exported); err != nil || len(exported) != 3 {
+		t.Errorf("expvar export %q: %v", p.Var().String(), err)
+	}
+}
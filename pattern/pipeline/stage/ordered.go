@@ -0,0 +1,115 @@
+package stage
+
+import (
+	. "learn/go/concurrency/pattern/ordone"
+	"sync"
+)
+
+// OrderedMap stage is Map fanned out to workers goroutines, but unlike the
+// naive fan-out fan-in in fan/, it sends results in the same order as their
+// inputs arrived from in.
+//
+// Every input is tagged with a sequence number before fanning out. Results
+// coming back from the workers out of order are parked until all results
+// before them have been sent, i.e. re-sequenced.
+//
+// window bounds how many items can be in flight (being processed or parked)
+// at once, hence bounds the memory used for re-sequencing. When one slow item
+// holds up the head of the line, the fast workers stop pulling from in after
+// window items, instead of piling up results. window should be at least
+// workers, otherwise some workers will always be idle.
+func OrderedMap[T, U any](
+	done <-chan any,
+	in <-chan T,
+	workers int,
+	window int,
+	fn func(T) U,
+) <-chan U {
+	type job struct {
+		seq int
+		v   T
+	}
+	type result struct {
+		seq int
+		u   U
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+	if window < 1 {
+		window = 1
+	}
+
+	// A slot is acquired before an item is sent to workers, and released when
+	// its result is sent to out.
+	slots := make(chan struct{}, window)
+	jobs := make(chan job)
+	results := make(chan result)
+	out := make(chan U)
+
+	// Dispatch.
+	go func() {
+		defer close(jobs)
+		seq := 0
+		for v := range OrDone(done, in) {
+			select {
+			case <-done:
+				return
+			case slots <- struct{}{}:
+			}
+			select {
+			case <-done:
+				return
+			case jobs <- job{seq, v}:
+			}
+			seq++
+		}
+	}()
+
+	// Fan out.
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				select {
+				case <-done:
+					return
+				case results <- result{j.seq, fn(j.v)}:
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Fan in and re-sequence.
+	go func() {
+		defer close(out)
+		pending := make(map[int]U, window)
+		next := 0
+		for r := range OrDone(done, results) {
+			pending[r.seq] = r.u
+			for {
+				u, ok := pending[next]
+				if !ok {
+					break
+				}
+				select {
+				case <-done:
+					return
+				case out <- u:
+				}
+				delete(pending, next)
+				next++
+				<-slots
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,49 @@
+package stage
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// TestOrderedMap shows results come out in input order even though workers
+// finish in random order.
+func TestOrderedMap(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	ints := make(chan int)
+	go func() {
+		defer close(ints)
+		for i := 0; i < 100; i++ {
+			ints <- i
+		}
+	}()
+
+	slowSquare := func(v int) int {
+		time.Sleep(time.Duration(rand.Intn(1000)) * time.Microsecond)
+		return v * v
+	}
+
+	i := 0
+	for v := range OrderedMap(done, ints, 8, 16, slowSquare) {
+		if v != i*i {
+			t.Fatalf("item %d = %v, want %v", i, v, i*i)
+		}
+		i++
+	}
+	if i != 100 {
+		t.Errorf("got %d items, want 100", i)
+	}
+}
+
+// TestOrderedMapDone shows closing done stop all goroutines of the stage even
+// if the consumer loses interest half way.
+func TestOrderedMapDone(_ *testing.T) {
+	done := make(chan interface{})
+	out := OrderedMap(done, Repeat(done, 1), 4, 4, func(v int) int { return v })
+	<-out
+	close(done)
+	for range out {
+	}
+}
@@ -0,0 +1,185 @@
+package stage
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// PanicError is a panic recovered from a stage goroutine. Like wrapError in
+// scale/error, the stack trace is captured where it happened, so it's not
+// lost by the time the error reaches whoever handles it.
+type PanicError struct {
+	Value      any // What was passed to panic.
+	StackTrace string
+}
+
+func newPanicError(v any) PanicError {
+	return PanicError{Value: v, StackTrace: string(debug.Stack())}
+}
+
+func (err PanicError) Error() string {
+	return fmt.Sprintf("panic in stage: %v", err.Value)
+}
+
+// Unwrap return the panic value if it is an error, e.g. a runtime.Error.
+func (err PanicError) Unwrap() error {
+	if e, ok := err.Value.(error); ok {
+		return e
+	}
+	return nil
+}
+
+// There are two ways to keep a panic in a stage from crashing the process:
+//
+// * As an error item: Try, TryFn, MapResult and Assert recover panics into a
+//   Result carrying a PanicError, the stream goes on.
+// * As a pipeline-level error: functions wrapped by Guarded/GuardedFn record
+//   the panic in a Guard, which shuts down every stage built on its Done().
+
+// safeCall call fn, converting a panic into a PanicError.
+func safeCall[U any](fn func() (U, error)) (u U, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = newPanicError(v)
+		}
+	}()
+	return fn()
+}
+
+// Recover adapt fn to Try and MapResult: a panic in fn is returned as a
+// PanicError.
+func Recover[T, U any](fn func(T) U) func(T) (U, error) {
+	return func(v T) (U, error) {
+		return safeCall(func() (U, error) { return fn(v), nil })
+	}
+}
+
+// TryFn is RepeatFn for a fallible fn, every outcome is sent as a Result.
+// Panics in fn are sent as PanicError too.
+func TryFn[T any](done <-chan any, fn func() (T, error)) <-chan Result[T] {
+	valueStream := make(chan Result[T])
+	go func() {
+		defer close(valueStream)
+		for {
+			v, err := safeCall(fn)
+			select {
+			case <-done:
+				return
+			case valueStream <- Result[T]{Value: v, Error: err}:
+			}
+		}
+	}()
+	return valueStream
+}
+
+// Assert stage is ToInt/ToString for any type, except that an item of the
+// wrong type is sent as an error instead of panicking.
+func Assert[T any](done <-chan any, in <-chan any) <-chan Result[T] {
+	return Try(done, in, func(v any) (T, error) {
+		t, ok := v.(T)
+		if !ok {
+			return t, fmt.Errorf("assert: %v is %T, not %T", v, v, t)
+		}
+		return t, nil
+	})
+}
+
+// Guard turn a panic in any of the functions it guards into a pipeline-level
+// error: the first panic is recorded, and Done() is closed so every stage
+// using it as done shuts down. The panicking stage goroutine exits right away
+// (closing its output as usual), without sending anything for the item.
+//
+//		g := NewGuard(done)
+//		ints := Map(g.Done(), RepeatFn(g.Done(), GuardedFn(g, gen)), Guarded(g, parse))
+//		for v := range ints {
+//			...
+//		}
+//		if err := g.Err(); err != nil {
+//			// err is a PanicError.
+//		}
+type Guard struct {
+	parent    <-chan any
+	done      chan any
+	once      sync.Once // closes done.
+	watchOnce sync.Once
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewGuard create a Guard whose Done() is also closed when done is closed.
+func NewGuard(done <-chan any) *Guard {
+	return &Guard{parent: done, done: make(chan any)}
+}
+
+// Done is closed when done is closed, a guarded function panicked, or Close
+// is called.
+//
+// The goroutine watching done is only started by the first call, so a Guard
+// whose Done is never used (e.g. that of a Pipeline never started) leaves no
+// goroutine behind. It exits once Done() is closed.
+func (g *Guard) Done() <-chan any {
+	g.watchOnce.Do(func() {
+		if g.parent == nil {
+			return // Never closed, nothing to watch.
+		}
+		go func() {
+			select {
+			case <-g.parent:
+				g.Close()
+			case <-g.done:
+			}
+		}()
+	})
+	return g.done
+}
+
+// Close closes Done() as closing done would, without an error. Use it when
+// done may never be closed, so the goroutine watching it can exit.
+func (g *Guard) Close() {
+	g.once.Do(func() { close(g.done) })
+}
+
+// Err return the PanicError of the first panic, nil if none.
+func (g *Guard) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+// recover must be deferred directly by the guarded function.
+func (g *Guard) recover() {
+	v := recover()
+	if v == nil {
+		return
+	}
+	g.once.Do(func() {
+		g.mu.Lock()
+		g.err = newPanicError(v)
+		g.mu.Unlock()
+		close(g.done)
+	})
+	// Don't return into the stage, which would send a zero value on.
+	// Goexit still runs the stage's deferred close(out).
+	runtime.Goexit()
+}
+
+// Guarded wrap fn so a panic in it is recorded in g. The returned function
+// must only be called from stage goroutines, since on panic it ends the
+// calling goroutine.
+func Guarded[T, U any](g *Guard, fn func(T) U) func(T) U {
+	return func(v T) U {
+		defer g.recover()
+		return fn(v)
+	}
+}
+
+// GuardedFn is Guarded for generator functions, e.g. those of RepeatFn.
+func GuardedFn[T any](g *Guard, fn func() T) func() T {
+	return func() T {
+		defer g.recover()
+		return fn()
+	}
+}
@@ -0,0 +1,110 @@
+package stage
+
+import (
+	"errors"
+	"learn/go/concurrency/internal/leaktest"
+	"strings"
+	"testing"
+)
+
+// TestPanicAsErrorItem shows a failed assertion and a panicking fn turned into
+// error items, instead of crashing the process like ToInt would.
+func TestPanicAsErrorItem(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	values := TakeAny(done, RepeatAny(done, 1, "two", 0), 3)
+	inverse := Recover(func(v int) int { return 12 / v })
+	results := MapResult(done, Assert[int](done, values), inverse)
+
+	var errs []error
+	for r := range results {
+		if r.Error != nil {
+			errs = append(errs, r.Error)
+		}
+	}
+	if len(errs) != 2 {
+		t.Fatalf("got errors %v, want 2", errs)
+	}
+	var pe PanicError
+	if !errors.As(errs[1], &pe) || !strings.Contains(pe.StackTrace, "panic_test.go") {
+		t.Errorf("got %v, want a PanicError with stack trace", errs[1])
+	}
+}
+
+func TestTryFn(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	n := 0
+	fn := func() (int, error) {
+		n++
+		if n == 2 {
+			panic("boom")
+		}
+		return n, nil
+	}
+	var errCount int
+	for r := range Take(done, TryFn(done, fn), 3) {
+		if r.Error != nil {
+			errCount++
+		}
+	}
+	if errCount != 1 {
+		t.Errorf("got %d errors, want 1", errCount)
+	}
+}
+
+// TestGuard shows a panic in RepeatFn's fn shutting down the whole pipeline
+// cleanly, and being reported as a pipeline-level error.
+func TestGuard(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	g := NewGuard(done)
+	n := 0
+	gen := func() int {
+		n++
+		if n == 5 {
+			var m map[string]int
+			m["boom"] = n // panic: assignment to entry in nil map
+		}
+		return n
+	}
+	double := func(v int) int { return v * 2 }
+
+	got := 0
+	for range Map(g.Done(), RepeatFn(g.Done(), GuardedFn(g, gen)), Guarded(g, double)) {
+		got++
+	}
+	// Items in flight when the Guard closes Done() are dropped, like closing
+	// done would do.
+	if got > 4 {
+		t.Errorf("got %d items, want at most 4", got)
+	}
+	var pe PanicError
+	if !errors.As(g.Err(), &pe) {
+		t.Fatalf("g.Err() = %v, want a PanicError", g.Err())
+	}
+	if !strings.Contains(pe.StackTrace, "panic_test.go") {
+		t.Errorf("stack trace doesn't show where it panicked:\n%s", pe.StackTrace)
+	}
+}
+
+// TestGuardNoLeak shows Guards which are never done leaving no goroutine
+// behind: one on a nil done, one closed by Close, and that of a Pipeline
+// never started.
+func TestGuardNoLeak(t *testing.T) {
+	leaktest.Check(t)
+
+	NewGuard(nil).Done()
+	g := NewGuard(make(chan any))
+	g.Done()
+	g.Close()
+	<-g.Done()
+	if err := g.Err(); err != nil {
+		t.Errorf("g.Err() = %v after Close, want nil", err)
+	}
+
+	NewPipeline(func(done <-chan any) <-chan int { return Repeat(done, 1) })
+}
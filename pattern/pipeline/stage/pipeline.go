@@ -0,0 +1,216 @@
+package stage
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	. "learn/go/concurrency/pattern/or-channel"
+	. "learn/go/concurrency/pattern/ordone"
+	"strings"
+	"sync"
+)
+
+// Source is a generator as a value: it produce items until done is closed,
+// e.g. func(done <-chan any) <-chan int { return Repeat(done, 1) }.
+type Source[T any] func(done <-chan any) <-chan T
+
+// Stage is a stage as a value, so it can be chained by Pipeline, e.g.
+// func(done <-chan any, in <-chan int) <-chan int { return Buffer(done, in, 2) }.
+type Stage[T any] func(done <-chan any, in <-chan T) <-chan T
+
+// Pipeline chain a source and named stages into a sink, instead of nesting
+// calls like Sleep(done, Buffer(done, Sleep(done, zeros, ...))).
+//
+// It also knows two ways to shut down:
+//
+// * Stop abort right away, like closing done: items in flight are dropped.
+// * Drain only stop the source, items already in the pipeline (e.g. sitting
+//   in a Buffer) keep flowing to the sink, then the pipeline finishes.
+//
+//		p := NewPipeline(source).
+//			Then("parse", parse).
+//			Then("queue", queue).
+//			Start(sink)
+//		...
+//		p.Drain()
+//		err := p.Wait()
+//
+// The Then method keeps the item type. Stages changing it, e.g. a Map from int
+// to string, Batch or Try, are chained by the Then function instead:
+//
+//		lines := NewPipeline(source).Then("queue", queue)
+//		p := Then(lines, "parse", parse).Start(sink) // parse: string -> record
+type Pipeline[T any] struct {
+	*pipeline
+
+	// stream wire the source and the stages so far: the stages get done,
+	// the source gets sourceDone.
+	stream func(done, sourceDone <-chan any) <-chan T
+}
+
+// pipeline is the state shared by the Pipelines of one chain, whatever their
+// item type.
+type pipeline struct {
+	names []string
+
+	metered bool
+	metrics []*Metrics // One per stage when metered.
+
+	stop      chan any
+	stopOnce  sync.Once
+	drain     chan any
+	drainOnce sync.Once
+
+	// guard.Done() is the done channel of every stage, it's closed by Stop
+	// or by a panic in a function guarded by it.
+	guard *Guard
+
+	startOnce sync.Once
+	started   chan any
+	finished  chan any
+	err       error
+}
+
+// ErrNotStarted is returned by Wait on a Pipeline which was never started.
+var ErrNotStarted = errors.New("pipeline not started")
+
+// NewPipeline create a Pipeline reading from source.
+func NewPipeline[T any](source Source[T]) *Pipeline[T] {
+	stop := make(chan any)
+	return &Pipeline[T]{
+		pipeline: &pipeline{
+			stop:     stop,
+			drain:    make(chan any),
+			guard:    NewGuard(stop),
+			started:  make(chan any),
+			finished: make(chan any),
+		},
+		stream: func(_, sourceDone <-chan any) <-chan T { return source(sourceDone) },
+	}
+}
+
+// Then append stage to the pipeline, name is for telling stages apart.
+func (p *Pipeline[T]) Then(name string, stage Stage[T]) *Pipeline[T] {
+	*p = *Then(p, name, stage)
+	return p
+}
+
+// Then append stage to p, like the Then method, for stages whose output type
+// U differs from their input type T. The returned Pipeline is the same
+// chain: stopping, draining or waiting on either is the same.
+func Then[T, U any](
+	p *Pipeline[T],
+	name string,
+	stage func(done <-chan any, in <-chan T) <-chan U,
+) *Pipeline[U] {
+	p.names = append(p.names, name)
+	stream := p.stream
+	return &Pipeline[U]{
+		pipeline: p.pipeline,
+		stream: func(done, sourceDone <-chan any) <-chan U {
+			in := stream(done, sourceDone)
+			if p.metered {
+				m := NewMetrics(name)
+				p.metrics = append(p.metrics, m)
+				return Meter(m, stage)(done, in)
+			}
+			return stage(done, in)
+		},
+	}
+}
+
+// Metered make every stage record Metrics, see Metrics. It must be called
+// before Start.
+func (p *Pipeline[T]) Metered() *Pipeline[T] {
+	p.metered = true
+	return p
+}
+
+// Metrics return a snapshot of every stage's Metrics, in pipeline order. It's
+// empty unless the pipeline is Metered.
+func (p *Pipeline[T]) Metrics() []MetricsSnapshot {
+	snapshots := make([]MetricsSnapshot, len(p.metrics))
+	for i, m := range p.metrics {
+		snapshots[i] = m.Snapshot()
+	}
+	return snapshots
+}
+
+// Var export Metrics for expvar, e.g. expvar.Publish("ingest", p.Var()).
+func (p *Pipeline[T]) Var() expvar.Var {
+	return expvar.Func(func() any { return p.Metrics() })
+}
+
+// Guard return the Guard of the pipeline. Functions wrapped by Guarded(p.Guard(),
+// fn) abort the pipeline when they panic, and Wait return the PanicError.
+func (p *Pipeline[T]) Guard() *Guard {
+	return p.guard
+}
+
+// Start wire the stages up and feed their output to sink, which run in its
+// own goroutine. An error returned by sink aborts the pipeline and is
+// returned by Wait. sink can be nil, items are then discarded. Only the first
+// call starts the pipeline.
+func (p *Pipeline[T]) Start(sink func(T) error) *Pipeline[T] {
+	p.startOnce.Do(func() { p.start(sink) })
+	return p
+}
+
+func (p *Pipeline[T]) start(sink func(T) error) {
+	defer close(p.started)
+	done := p.guard.Done()
+
+	// Only the source watches drain, so draining stop the items at the
+	// source, while the stages downstream carry on until their input closes.
+	stream := p.stream(done, Or(p.drain, done))
+
+	go func() {
+		defer close(p.finished)
+		// Whatever finished the pipeline, make sure every stage and the
+		// source exit.
+		defer p.Stop()
+
+		for v := range OrDone(done, stream) {
+			if sink == nil {
+				continue
+			}
+			_, err := safeCall(func() (struct{}, error) { return struct{}{}, sink(v) })
+			if err != nil {
+				p.err = fmt.Errorf("pipeline %v: sink: %w", p, err)
+				return
+			}
+		}
+		if err := p.guard.Err(); err != nil {
+			p.err = fmt.Errorf("pipeline %v: %w", p, err)
+		}
+	}()
+}
+
+// Stop abort the pipeline, items in flight are dropped.
+func (p *Pipeline[T]) Stop() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+// Drain stop the source and let the items already in the pipeline flow to the
+// sink. Use Wait to know when they are all delivered.
+func (p *Pipeline[T]) Drain() {
+	p.drainOnce.Do(func() { close(p.drain) })
+}
+
+// Wait block until the pipeline finished, and return the first error: the one
+// returned by sink, or a PanicError caught by Guard. Stop and Drain are not
+// errors. It returns ErrNotStarted right away if Start was not called.
+func (p *Pipeline[T]) Wait() error {
+	select {
+	case <-p.started:
+	default:
+		return ErrNotStarted
+	}
+	<-p.finished
+	return p.err
+}
+
+// String describe the pipeline by its stage names, e.g. "source|parse|queue".
+func (p *Pipeline[T]) String() string {
+	return strings.Join(append([]string{"source"}, p.names...), "|")
+}
@@ -0,0 +1,171 @@
+package stage
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingSource send 0, 1, 2... until done, and count what it actually sent.
+func countingSource(sent *int64) Source[int] {
+	return func(done <-chan any) <-chan int {
+		out := make(chan int)
+		go func() {
+			defer close(out)
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				case out <- i:
+					atomic.AddInt64(sent, 1)
+				}
+			}
+		}()
+		return out
+	}
+}
+
+func buffer(n int) Stage[int] {
+	return func(done <-chan any, in <-chan int) <-chan int { return Buffer(done, in, n) }
+}
+
+// TestPipelineDrain shows Drain deliver every item the source has sent, even
+// those still sitting in the buffers when it's called.
+func TestPipelineDrain(t *testing.T) {
+	var sent, received int64
+	p := NewPipeline(countingSource(&sent)).
+		Then("buffer1", buffer(5)).
+		Then("buffer2", buffer(5)).
+		Start(func(int) error {
+			atomic.AddInt64(&received, 1)
+			time.Sleep(time.Millisecond) // the slow sink
+			return nil
+		})
+
+	time.Sleep(20 * time.Millisecond)
+	p.Drain()
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Wait() = %v, want nil", err)
+	}
+	if sent != received {
+		t.Errorf("sent %d, received %d, want all sent items received", sent, received)
+	}
+}
+
+// TestPipelineStop shows Stop drop the items in flight.
+func TestPipelineStop(t *testing.T) {
+	var sent, received int64
+	p := NewPipeline(countingSource(&sent)).
+		Then("buffer", buffer(10)).
+		Start(func(int) error {
+			atomic.AddInt64(&received, 1)
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+
+	time.Sleep(20 * time.Millisecond)
+	p.Stop()
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Wait() = %v, want nil", err)
+	}
+	if atomic.LoadInt64(&received) >= atomic.LoadInt64(&sent) {
+		t.Errorf("sent %d, received %d, want buffered items dropped", sent, received)
+	}
+}
+
+func TestPipelineWaitError(t *testing.T) {
+	errFull := errors.New("disk full")
+	var sent int64
+	p := NewPipeline(countingSource(&sent)).
+		Then("buffer", buffer(2)).
+		Start(func(v int) error {
+			if v == 3 {
+				return errFull
+			}
+			return nil
+		})
+	if err := p.Wait(); !errors.Is(err, errFull) {
+		t.Errorf("Wait() = %v, want %v", err, errFull)
+	}
+}
+
+// TestPipelinePanic shows a panic in a guarded stage function reported by
+// Wait, and the sink panicking too.
+func TestPipelinePanic(t *testing.T) {
+	var sent int64
+	p := NewPipeline(countingSource(&sent))
+	mustBeSmall := Guarded(p.Guard(), func(v int) int {
+		if v > 5 {
+			panic("too big")
+		}
+		return v
+	})
+	p.Then("check", func(done <-chan any, in <-chan int) <-chan int {
+		return Map(done, in, mustBeSmall)
+	}).Start(nil)
+
+	var pe PanicError
+	if err := p.Wait(); !errors.As(err, &pe) {
+		t.Errorf("Wait() = %v, want a PanicError", err)
+	}
+
+	p = NewPipeline(countingSource(&sent)).Start(func(v int) error {
+		var s []int
+		_ = s[v] // index out of range
+		return nil
+	})
+	if err := p.Wait(); !errors.As(err, &pe) {
+		t.Errorf("Wait() = %v, want a PanicError", err)
+	}
+}
+
+// TestPipelineThenChangesType shows stages changing the item type chained by
+// the Then function, in the same pipeline as the Then method.
+func TestPipelineThenChangesType(t *testing.T) {
+	var sent int64
+	ints := NewPipeline(countingSource(&sent)).Then("buffer", buffer(2))
+	strs := Then(ints, "itoa", func(done <-chan any, in <-chan int) <-chan string {
+		return Map(done, in, func(v int) string { return fmt.Sprint(v) })
+	})
+	batches := Then(strs, "batch", func(done <-chan any, in <-chan string) <-chan []string {
+		return Batch(done, in, 3, time.Hour)
+	})
+
+	var first []string
+	p := batches.Metered().Start(func(b []string) error {
+		if first == nil {
+			first = b
+		}
+		if len(first) > 0 && first[0] == "0" {
+			return errors.New("got it")
+		}
+		return nil
+	})
+	if err := p.Wait(); err == nil || fmt.Sprint(first) != "[0 1 2]" {
+		t.Errorf("Wait() = %v, first batch %v, want [0 1 2]", err, first)
+	}
+	if got := p.String(); got != "source|buffer|itoa|batch" {
+		t.Errorf("String() = %v", got)
+	}
+	if got := len(ints.Metrics()); got != 3 {
+		t.Errorf("got %d stage metrics, want 3", got)
+	}
+}
+
+// TestPipelineStartTwice shows Start only starting once, and Wait not
+// blocking on a pipeline never started.
+func TestPipelineStartTwice(t *testing.T) {
+	var sent int64
+	p := NewPipeline(countingSource(&sent))
+	if err := p.Wait(); !errors.Is(err, ErrNotStarted) {
+		t.Errorf("Wait() = %v before Start, want %v", err, ErrNotStarted)
+	}
+
+	p.Start(nil).Start(nil)
+	p.Stop()
+	if err := p.Wait(); err != nil {
+		t.Errorf("Wait() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,114 @@
+package stage
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Prioritized tag an item with a priority for PriorityBuffer, higher goes
+// first.
+type Prioritized[T any] struct {
+	Value    T
+	Priority int
+}
+
+// PriorityBuffer stage buffer up to n items from in, and always send the one
+// with the highest priority available, items of equal priority in FIFO order.
+//
+// Strict priority starves: as long as high-priority items keep coming, a
+// low-priority one is never sent, just like the polite worker in
+// problems/locks never gets the lock from the greedy one. So items age: every
+// aging duration spent in the buffer raise an item's priority by one. Pass 0
+// to disable aging.
+func PriorityBuffer[T any](
+	done <-chan any,
+	in <-chan Prioritized[T],
+	n int,
+	aging time.Duration,
+) <-chan T {
+	if n < 1 {
+		n = 1
+	}
+
+	out := make(chan T)
+	go func() {
+		defer close(out)
+
+		var q priorityQueue[T]
+		var seq int64
+		for {
+			recv := in
+			if q.Len() >= n {
+				recv = nil
+			}
+			var send chan<- T
+			var first T
+			if q.Len() > 0 {
+				send = out
+				first = q[0].v
+			} else if in == nil {
+				return // in is closed and everything is delivered.
+			}
+
+			select {
+			case <-done:
+				return
+			case p, ok := <-recv:
+				if !ok {
+					in = nil
+					continue
+				}
+				heap.Push(&q, newPriorityItem(p, aging, seq))
+				seq++
+			case send <- first:
+				heap.Pop(&q)
+			}
+		}
+	}()
+	return out
+}
+
+// priorityItem rank items by their effective priority with aging:
+//
+//		Priority + (now - enqueued)/aging
+//
+// which changes as time goes, but the order between two items doesn't, since
+// now is the same for both. So it's enough to compare the time-invariant
+// part, Priority*aging - enqueued, and a plain heap stays valid.
+type priorityItem[T any] struct {
+	v    T
+	rank int64
+	seq  int64 // tie breaker, FIFO.
+}
+
+func newPriorityItem[T any](p Prioritized[T], aging time.Duration, seq int64) priorityItem[T] {
+	rank := int64(p.Priority)
+	if aging > 0 {
+		rank = int64(p.Priority)*int64(aging) - time.Now().UnixNano()
+	}
+	return priorityItem[T]{v: p.Value, rank: rank, seq: seq}
+}
+
+// priorityQueue implements heap.Interface, highest rank first.
+type priorityQueue[T any] []priorityItem[T]
+
+func (q priorityQueue[T]) Len() int { return len(q) }
+
+func (q priorityQueue[T]) Less(i, j int) bool {
+	if q[i].rank != q[j].rank {
+		return q[i].rank > q[j].rank
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q priorityQueue[T]) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *priorityQueue[T]) Push(x any) { *q = append(*q, x.(priorityItem[T])) }
+
+func (q *priorityQueue[T]) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	old[len(old)-1] = priorityItem[T]{}
+	*q = old[:len(old)-1]
+	return item
+}
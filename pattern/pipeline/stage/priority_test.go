@@ -0,0 +1,71 @@
+package stage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestPriorityBuffer shows interactive traffic overtaking batch traffic which
+// was queued first.
+func TestPriorityBuffer(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	in := make(chan Prioritized[string])
+	out := PriorityBuffer(done, in, 10, 0)
+	for _, p := range []Prioritized[string]{
+		{"batch1", 0}, {"batch2", 0}, {"click1", 10}, {"batch3", 0}, {"click2", 10},
+	} {
+		in <- p
+	}
+	close(in)
+
+	got := collect(out)
+	if fmt.Sprint(got) != "[click1 click2 batch1 batch2 batch3]" {
+		t.Errorf("got %v", got)
+	}
+}
+
+// TestPriorityBufferAging shows a low priority item getting through a steady
+// stream of high priority ones thanks to aging.
+func TestPriorityBufferAging(t *testing.T) {
+	for _, tc := range []struct {
+		aging   time.Duration
+		starved bool
+	}{
+		{0, true},
+		{time.Millisecond, false},
+	} {
+		done := make(chan interface{})
+
+		in := make(chan Prioritized[string])
+		go func() {
+			defer close(in)
+			in <- Prioritized[string]{"batch", 0}
+			for {
+				select {
+				case <-done:
+					return
+				case in <- Prioritized[string]{"click", 5}:
+				}
+			}
+		}()
+
+		out := PriorityBuffer(done, in, 4, tc.aging)
+		time.Sleep(10 * time.Millisecond) // let the buffer fill up
+
+		starved := true
+		for v := range Take(done, out, 50) {
+			time.Sleep(200 * time.Microsecond) // the slow consumer
+			if v == "batch" {
+				starved = false
+			}
+		}
+		close(done)
+
+		if starved != tc.starved {
+			t.Errorf("aging %v: starved = %v, want %v", tc.aging, starved, tc.starved)
+		}
+	}
+}
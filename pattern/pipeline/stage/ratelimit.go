@@ -0,0 +1,105 @@
+package stage
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimiter is the same interface the limiters in scale/ratelimiting
+// satisfy, so a *rate.Limiter as well as a multiLimiter aggregating several of
+// them can pace a pipeline.
+type RateLimiter interface {
+	Wait(context.Context) error
+	Limit() rate.Limit
+}
+
+// RateLimit stage pace items from in through limiter: every item waits for
+// limiter before it's sent to out.
+//
+// If maxWait > 0, an item which would have to wait longer than maxWait is
+// dropped instead, so a stream bursting above the limit sheds load rather
+// than backing up upstream. A *rate.Limiter tells that without waiting and
+// without consuming a token. maxWait <= 0 means always wait.
+func RateLimit[T any](
+	done <-chan any,
+	in <-chan T,
+	limiter RateLimiter,
+	maxWait time.Duration,
+) <-chan T {
+	return rateLimit(context.Background(), done, in, limiter, maxWait)
+}
+
+// RateLimitCtx is RateLimit driven by ctx. ctx is also the one limiter waits
+// on, so a deadline on ctx bounds the wait, too: with maxWait <= 0 the stage
+// stops once the limiter tells it can't make the deadline.
+func RateLimitCtx[T any](
+	ctx context.Context,
+	in <-chan T,
+	limiter RateLimiter,
+	maxWait time.Duration,
+) <-chan T {
+	return rateLimit(ctx, nil, in, limiter, maxWait)
+}
+
+func rateLimit[T any](
+	ctx context.Context,
+	done <-chan any,
+	in <-chan T,
+	limiter RateLimiter,
+	maxWait time.Duration,
+) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+
+		// limiter.Wait only knows about contexts, so done has to be turned
+		// into one. The goroutine exits with the stage thanks to cancel.
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		if done != nil {
+			go func() {
+				select {
+				case <-done:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+		}
+
+		wait := func() error {
+			if maxWait <= 0 {
+				return limiter.Wait(ctx)
+			}
+			waitCtx, cancel := context.WithTimeout(ctx, maxWait)
+			defer cancel()
+			return limiter.Wait(waitCtx)
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if err := wait(); err != nil {
+					if ctx.Err() != nil || maxWait <= 0 {
+						// Canceled, or limiter can't ever let v through in
+						// time (e.g. ctx deadline is too close).
+						return
+					}
+					continue // would wait past maxWait, drop.
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case out <- v:
+				}
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,59 @@
+package stage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// TestRateLimit shows items paced by a 100 events per second limiter.
+func TestRateLimit(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	limiter := rate.NewLimiter(rate.Every(10*time.Millisecond), 1)
+	start := time.Now()
+	n := 0
+	for range RateLimit(done, Take(done, Repeat(done, 1), 6), limiter, 0) {
+		n++
+	}
+	if n != 6 {
+		t.Errorf("got %d items, want 6", n)
+	}
+	if took := time.Since(start); took < 50*time.Millisecond {
+		t.Errorf("took %v, want at least 50ms", took)
+	}
+}
+
+// TestRateLimitDrop shows a burst shedding the items which would wait too
+// long, instead of blocking upstream.
+func TestRateLimitDrop(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	limiter := rate.NewLimiter(rate.Every(time.Hour), 2)
+	n := 0
+	for range RateLimit(done, Take(done, Repeat(done, 1), 10), limiter, time.Millisecond) {
+		n++
+	}
+	if n != 2 {
+		t.Errorf("got %d items, want 2 (the burst)", n)
+	}
+}
+
+// TestRateLimitCtx shows canceling ctx release a stage blocked on the limiter.
+func TestRateLimitCtx(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	limiter := rate.NewLimiter(rate.Every(time.Hour), 1)
+	n := 0
+	for range RateLimitCtx(ctx, RepeatCtx(ctx, 1), limiter, 0) {
+		n++
+	}
+	if n != 1 {
+		t.Errorf("got %d items, want 1", n)
+	}
+}
@@ -0,0 +1,134 @@
+package stage
+
+import (
+	"fmt"
+	. "learn/go/concurrency/pattern/ordone"
+)
+
+// Result couple a value with the error occurred producing it, so errors flow
+// through a pipeline alongside values and are handled by whoever has the
+// complete picture, see error-handling/ for why.
+type Result[T any] struct {
+	Value T
+	Error error
+}
+
+// TooManyErrors is sent by AbortAfter in place of the error which exceeded
+// its budget, right before it aborts the stream.
+type TooManyErrors struct {
+	Count int   // Errors seen, including Last.
+	Last  error // The error which triggered the abort.
+}
+
+func (err TooManyErrors) Error() string {
+	return fmt.Sprintf("aborted after %d errors: %v", err.Count, err.Last)
+}
+
+func (err TooManyErrors) Unwrap() error {
+	return err.Last
+}
+
+// Try stage apply the fallible fn to every item from in, and send the outcome
+// as a Result. A panic in fn is sent as a PanicError.
+func Try[T, U any](done <-chan any, in <-chan T, fn func(T) (U, error)) <-chan Result[U] {
+	out := make(chan Result[U])
+	go func() {
+		defer close(out)
+		for v := range OrDone(done, in) {
+			u, err := safeCall(func() (U, error) { return fn(v) })
+			select {
+			case <-done:
+				return
+			case out <- Result[U]{Value: u, Error: err}:
+			}
+		}
+	}()
+	return out
+}
+
+// MapResult stage is Try over a Result stream: fn is only applied to
+// successful results, failed ones are forwarded as is, so the first error of
+// an item is the one reported at the end of the pipeline. A panic in fn is
+// sent as a PanicError.
+func MapResult[T, U any](
+	done <-chan any,
+	in <-chan Result[T],
+	fn func(T) (U, error),
+) <-chan Result[U] {
+	out := make(chan Result[U])
+	go func() {
+		defer close(out)
+		for r := range OrDone(done, in) {
+			var next Result[U]
+			if r.Error != nil {
+				next.Error = r.Error
+			} else {
+				next.Value, next.Error = safeCall(func() (U, error) {
+					return fn(r.Value)
+				})
+			}
+			select {
+			case <-done:
+				return
+			case out <- next:
+			}
+		}
+	}()
+	return out
+}
+
+// SkipErrors stage skip over failed results and unwrap the successful ones.
+// onError, if not nil, is called with every error skipped, e.g. to log it.
+func SkipErrors[T any](done <-chan any, in <-chan Result[T], onError func(error)) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for r := range OrDone(done, in) {
+			if r.Error != nil {
+				if onError != nil {
+					onError(r.Error)
+				}
+				continue
+			}
+			select {
+			case <-done:
+				return
+			case out <- r.Value:
+			}
+		}
+	}()
+	return out
+}
+
+// AbortAfter stage forward results from in, and short-circuit the stream once
+// n errors have been seen: the nth error is sent wrapped in TooManyErrors,
+// then out is closed. n = 1 means abort on the first error.
+//
+// This is the "errCount >= 3" loop in error-handling/ turned into a stage, so
+// every pipeline gives up the same way. Upstream is left to done as usual.
+func AbortAfter[T any](done <-chan any, in <-chan Result[T], n int) <-chan Result[T] {
+	out := make(chan Result[T])
+	go func() {
+		defer close(out)
+		errCount := 0
+		for r := range OrDone(done, in) {
+			abort := false
+			if r.Error != nil {
+				errCount++
+				if errCount >= n {
+					r.Error = TooManyErrors{Count: errCount, Last: r.Error}
+					abort = true
+				}
+			}
+			select {
+			case <-done:
+				return
+			case out <- r:
+			}
+			if abort {
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,49 @@
+package stage
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+func TestSkipErrors(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	inputs := Take(done, Repeat(done, "1", "x", "2", "y"), 4)
+	results := MapResult(done, Try(done, inputs, strconv.Atoi), func(v int) (int, error) {
+		return v * 10, nil
+	})
+
+	var skipped int
+	got := collect(SkipErrors(done, results, func(error) { skipped++ }))
+	if fmt.Sprint(got) != "[10 20]" || skipped != 2 {
+		t.Errorf("got %v, skipped %d, want [10 20], skipped 2", got, skipped)
+	}
+}
+
+func TestAbortAfter(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	inputs := Repeat(done, "1", "x", "y", "2", "z", "3")
+	results := AbortAfter(done, Try(done, inputs, strconv.Atoi), 3)
+
+	var last Result[int]
+	n := 0
+	for last = range results {
+		n++
+	}
+	if n != 5 {
+		t.Errorf("got %d results, want 5", n)
+	}
+	var tooMany TooManyErrors
+	if !errors.As(last.Error, &tooMany) || tooMany.Count != 3 {
+		t.Errorf("last error = %v, want TooManyErrors after 3", last.Error)
+	}
+	var numErr *strconv.NumError
+	if !errors.As(last.Error, &numErr) || numErr.Num != "z" {
+		t.Errorf("last error = %v, want it to wrap the error parsing z", last.Error)
+	}
+}
@@ -0,0 +1,32 @@
+package stage
+
+import (
+	. "learn/go/concurrency/pattern/fan"
+)
+
+// Shard stage is Map fanned out to shards goroutines by key: items are routed
+// by FanOut with KeyHash, a consistent hash of key(item), so all items of a
+// key go to the same shard, which applies fn to them strictly in the order
+// they arrived. Results of different shards are joined back by FanIn as they
+// come, so only per-key order is kept, in between the unordered FanIn and a
+// serial Map.
+//
+// With consistent hashing, changing shards only moves about 1/shards of the
+// keys to another shard, instead of nearly all of them with hash % shards.
+// That matters when shards keep per-key state, e.g. a cache.
+//
+// A slow item holds up the other keys of its shard, not the other shards.
+func Shard[T, U any](
+	done <-chan any,
+	in <-chan T,
+	shards int,
+	key func(T) string,
+	fn func(T) U,
+) <-chan U {
+	shardStreams := FanOut(done, in, shards, KeyHash(key))
+	results := make([]<-chan U, len(shardStreams))
+	for i, s := range shardStreams {
+		results[i] = Map(done, s, fn)
+	}
+	return FanIn(done, results...)
+}
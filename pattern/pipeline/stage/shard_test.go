@@ -0,0 +1,34 @@
+package stage
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestShard(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	type event struct {
+		user string
+		seq  int
+	}
+	var events []event
+	for seq := 0; seq < 100; seq++ {
+		events = append(events, event{fmt.Sprint("user", seq%7), seq})
+	}
+
+	last := make(map[string]int)
+	n := 0
+	key := func(e event) string { return e.user }
+	for e := range Shard(done, slice(done, events...), 4, key, func(e event) event { return e }) {
+		if seq, ok := last[e.user]; ok && seq > e.seq {
+			t.Errorf("%v: seq %d after %d", e.user, e.seq, seq)
+		}
+		last[e.user] = e.seq
+		n++
+	}
+	if n != len(events) {
+		t.Errorf("got %d events, want %d", n, len(events))
+	}
+}
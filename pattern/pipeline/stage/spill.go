@@ -0,0 +1,232 @@
+package stage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Codec serialize items for SpillBuffer.
+type Codec[T any] interface {
+	Marshal(v T) ([]byte, error)
+	Unmarshal(data []byte) (T, error)
+}
+
+// JSONCodec is a Codec using encoding/json, so T must survive a JSON round
+// trip (exported fields etc).
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Marshal(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec[T]) Unmarshal(data []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+// spillSegmentSize is the size after which a spill segment file is not
+// written to anymore, so it can be removed once read.
+const spillSegmentSize = 4 << 20
+
+// SpillBuffer stage is a Buffer which never blocks upstream: it keeps up to n
+// items in a ring in memory, and spills the overflow to segment files in a
+// temporary directory under dir (os.TempDir() if dir is ""). Items are
+// serialized by codec.
+//
+// Items are sent in FIFO order: once something is spilled, new items go to
+// disk behind it, and the ring is refilled from disk as the consumer catches
+// up. Read segments are removed as it goes, and the whole directory is
+// removed when the stage exits.
+//
+// A failure (disk full etc) is sent on the buffered error channel, then the
+// stage exits. The error channel is closed once the stage exited, so as with
+// walkFiles in md5dir/, `if err := <-errc; err != nil` is safe once out is
+// drained: it's nil on success.
+func SpillBuffer[T any](
+	done <-chan any,
+	in <-chan T,
+	n int,
+	dir string,
+	codec Codec[T],
+) (<-chan T, <-chan error) {
+	if n < 1 {
+		n = 1
+	}
+
+	out := make(chan T)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		defer close(out)
+
+		// The in-memory ring.
+		ring := make([]T, n)
+		head, size := 0, 0
+		push := func(v T) {
+			ring[(head+size)%n] = v
+			size++
+		}
+		pop := func() {
+			var zero T
+			ring[head] = zero
+			head = (head + 1) % n
+			size--
+		}
+
+		spill := &spillQueue[T]{parent: dir, codec: codec}
+		defer spill.close()
+
+		for {
+			// Refill the ring from disk, so the consumer never waits on it.
+			for size < n && spill.len() > 0 {
+				v, err := spill.pop()
+				if err != nil {
+					errc <- err
+					return
+				}
+				push(v)
+			}
+
+			var send chan<- T
+			var first T
+			if size > 0 {
+				send = out
+				first = ring[head]
+			} else if in == nil {
+				return // in is closed and everything is delivered.
+			}
+
+			select {
+			case <-done:
+				return
+			case v, ok := <-in:
+				if !ok {
+					in = nil
+					continue
+				}
+				if size < n && spill.len() == 0 {
+					push(v)
+					continue
+				}
+				if err := spill.push(v); err != nil {
+					errc <- err
+					return
+				}
+			case send <- first:
+				pop()
+			}
+		}
+	}()
+	return out, errc
+}
+
+// spillQueue is the on-disk FIFO behind SpillBuffer: items are appended,
+// length-prefixed, to the last segment and read back from the first one.
+type spillQueue[T any] struct {
+	parent string
+	codec  Codec[T]
+
+	dir      string // created on first push.
+	segments []*spillSegment
+	nextID   int
+	items    int
+}
+
+type spillSegment struct {
+	f       *os.File
+	w       *bufio.Writer
+	written int64 // bytes.
+	readOff int64
+	items   int // unread.
+}
+
+func (q *spillQueue[T]) len() int {
+	return q.items
+}
+
+func (q *spillQueue[T]) push(v T) error {
+	data, err := q.codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("spill: encode: %w", err)
+	}
+
+	if q.dir == "" {
+		if q.dir, err = os.MkdirTemp(q.parent, "spill-"); err != nil {
+			return fmt.Errorf("spill: %w", err)
+		}
+	}
+	if len(q.segments) == 0 || q.segments[len(q.segments)-1].written >= spillSegmentSize {
+		path := filepath.Join(q.dir, fmt.Sprintf("segment-%06d", q.nextID))
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return fmt.Errorf("spill: %w", err)
+		}
+		q.nextID++
+		q.segments = append(q.segments, &spillSegment{f: f, w: bufio.NewWriter(f)})
+	}
+
+	seg := q.segments[len(q.segments)-1]
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(data)))
+	if _, err := seg.w.Write(header[:]); err != nil {
+		return fmt.Errorf("spill: %w", err)
+	}
+	if _, err := seg.w.Write(data); err != nil {
+		return fmt.Errorf("spill: %w", err)
+	}
+	seg.written += int64(len(header) + len(data))
+	seg.items++
+	q.items++
+	return nil
+}
+
+func (q *spillQueue[T]) pop() (T, error) {
+	var v T
+	seg := q.segments[0]
+
+	// What's still in the write buffer is not in the file yet.
+	if seg.w.Buffered() > 0 {
+		if err := seg.w.Flush(); err != nil {
+			return v, fmt.Errorf("spill: %w", err)
+		}
+	}
+
+	var header [4]byte
+	if _, err := seg.f.ReadAt(header[:], seg.readOff); err != nil {
+		return v, fmt.Errorf("spill: %w", err)
+	}
+	data := make([]byte, binary.BigEndian.Uint32(header[:]))
+	if _, err := seg.f.ReadAt(data, seg.readOff+int64(len(header))); err != nil {
+		return v, fmt.Errorf("spill: %w", err)
+	}
+	seg.readOff += int64(len(header) + len(data))
+	seg.items--
+	q.items--
+
+	// A segment which is fully read and not written to anymore is done.
+	if seg.items == 0 && seg.written >= spillSegmentSize {
+		seg.f.Close()
+		os.Remove(seg.f.Name())
+		q.segments = q.segments[1:]
+	}
+
+	v, err := q.codec.Unmarshal(data)
+	if err != nil {
+		return v, fmt.Errorf("spill: decode: %w", err)
+	}
+	return v, nil
+}
+
+func (q *spillQueue[T]) close() {
+	for _, seg := range q.segments {
+		seg.f.Close()
+	}
+	if q.dir != "" {
+		os.RemoveAll(q.dir)
+	}
+}
@@ -0,0 +1,50 @@
+package stage
+
+import (
+	"os"
+	"testing"
+)
+
+// TestSpillBuffer shows a stalled consumer not blocking upstream: the overflow
+// goes to disk, and comes back in order once the consumer catches up.
+func TestSpillBuffer(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	type event struct {
+		ID   int
+		Name string
+	}
+
+	dir := t.TempDir()
+	in := make(chan event)
+	out, errc := SpillBuffer[event](done, in, 10, dir, JSONCodec[event]{})
+
+	// Nobody is reading out yet, still all the sends go through.
+	for i := 0; i < 1000; i++ {
+		in <- event{i, "e"}
+	}
+	close(in)
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("got %d entries in %s, want the spill directory", len(entries), dir)
+	}
+
+	i := 0
+	for e := range out {
+		if e.ID != i {
+			t.Fatalf("got %d, want %d", e.ID, i)
+		}
+		i++
+	}
+	if i != 1000 {
+		t.Errorf("got %d items, want 1000", i)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("got %d entries in %s, want spill files removed", len(entries), dir)
+	}
+}
@@ -1,4 +1,8 @@
 // We collect common generator & stages here, they are used in other files.
+//
+// Stages are generic over the element type, so a pipeline can stay typed end
+// to end without ToInt/ToString assertion stages in between. The done channel
+// remains a plain <-chan any: it only signals, it never carries values.
 package stage
 
 import (
@@ -9,11 +13,11 @@ import (
 
 // Repeat stage will repeat the values you pass ot it infinitely until you tell
 // it to stop.
-func Repeat(
-	done <-chan interface{},
-	values ...interface{},
-) <-chan interface{} {
-	valueStream := make(chan interface{})
+func Repeat[T any](
+	done <-chan any,
+	values ...T,
+) <-chan T {
+	valueStream := make(chan T)
 	go func() {
 		defer close(valueStream)
 		for {
@@ -30,20 +34,42 @@ func Repeat(
 }
 
 // Take stage take the first num of items off then exit.
-func Take(
-	done <-chan interface{},
-	valueStream <-chan interface{},
+//
+// It's tempting to write the loop body as a one-liner:
+//
+//		select {
+//		case <-done:
+//			return
+//		case takeStream <- <-valueStream: // NOTE the <- <-
+//		}
+//
+// but the receive is evaluated before select even starts, so it blocks
+// without watching done, and it keeps yielding zero values once valueStream
+// is closed. Hence the two selects.
+func Take[T any](
+	done <-chan any,
+	valueStream <-chan T,
 	num int,
-) <-chan interface{} {
-	takeStream := make(chan interface{})
+) <-chan T {
+	takeStream := make(chan T)
 	go func() {
 		defer close(takeStream)
 
 		for i := 0; i < num; i++ {
+			var v T
+			var ok bool
+			select {
+			case <-done:
+				return
+			case v, ok = <-valueStream:
+				if !ok {
+					return
+				}
+			}
 			select {
 			case <-done:
 				return
-			case takeStream <- <-valueStream: // NOTE the <- <-
+			case takeStream <- v:
 			}
 		}
 	}()
@@ -51,11 +77,11 @@ func Take(
 }
 
 // ReapeatFn repleatly generate values by calling fn.
-func RepeatFn(
-	done <-chan interface{},
-	fn func() interface{},
-) <-chan interface{} {
-	valueStream := make(chan interface{})
+func RepeatFn[T any](
+	done <-chan any,
+	fn func() T,
+) <-chan T {
+	valueStream := make(chan T)
 	go func() {
 		defer close(valueStream)
 		for {
@@ -72,7 +98,9 @@ func RepeatFn(
 // ToString stage assert a value is a string.
 //
 // When you need to deal in specific types, you can place a stage that performs
-// the type assertion for you.
+// the type assertion for you. With the generic stages above this is only
+// needed when the upstream is an interface{} stream, e.g. from the *Any
+// wrappers below.
 func ToString(
 	done <-chan interface{},
 	valueStream <-chan interface{},
@@ -111,8 +139,8 @@ func ToInt(done <-chan interface{}, valueStream <-chan interface{}) <-chan int {
 //
 // Try implement it yourself and you'll see exactly why we need OrDone Pattern.
 // With OrDone'a help, we can implement it like OrDoneSleep.
-func Sleep1(done <-chan any, in <-chan any, d time.Duration) <-chan any {
-	out := make(chan any)
+func Sleep1[T any](done <-chan any, in <-chan T, d time.Duration) <-chan T {
+	out := make(chan T)
 	go func() {
 		defer func() {
 			fmt.Println("closing(out)")
@@ -134,8 +162,8 @@ func Sleep1(done <-chan any, in <-chan any, d time.Duration) <-chan any {
 //
 // Try implement it yourself and you'll see exactly why we need OrDone Pattern.
 // With OrDone'a help, we can implement it like OrDoneSleep.
-func Sleep(done <-chan any, in <-chan any, d time.Duration) <-chan any {
-	out := make(chan any)
+func Sleep[T any](done <-chan any, in <-chan T, d time.Duration) <-chan T {
+	out := make(chan T)
 	go func() {
 		defer close(out)
 
@@ -159,8 +187,8 @@ func Sleep(done <-chan any, in <-chan any, d time.Duration) <-chan any {
 }
 
 // SleepOrDone is same as Sleep but implemented with the help of OrDone.
-func SleepOrDone(done <-chan any, in <-chan any, d time.Duration) <-chan any {
-	out := make(chan any)
+func SleepOrDone[T any](done <-chan any, in <-chan T, d time.Duration) <-chan T {
+	out := make(chan T)
 	go func() {
 		defer close(out)
 
@@ -177,8 +205,8 @@ func SleepOrDone(done <-chan any, in <-chan any, d time.Duration) <-chan any {
 }
 
 // Buffer stage buffer n items for in.
-func Buffer(done <-chan any, in <-chan any, n int) <-chan any {
-	out := make(chan any, n)
+func Buffer[T any](done <-chan any, in <-chan T, n int) <-chan T {
+	out := make(chan T, n)
 	go func() {
 		defer close(out)
 		for v := range OrDone(done, in) {
@@ -191,3 +219,37 @@ func Buffer(done <-chan any, in <-chan any, n int) <-chan any {
 	}()
 	return out
 }
+
+// The *Any wrappers below are the interface{} flavors of the generic stages,
+// kept for callers that mix value types in one stream or assert on the way
+// out (see queue/ and fan/). They are nothing but an explicit instantiation.
+
+// RepeatAny is Repeat over interface{} values.
+func RepeatAny(done <-chan any, values ...any) <-chan any {
+	return Repeat(done, values...)
+}
+
+// TakeAny is Take over an interface{} stream.
+func TakeAny(done <-chan any, valueStream <-chan any, num int) <-chan any {
+	return Take(done, valueStream, num)
+}
+
+// RepeatFnAny is RepeatFn over a fn returning interface{}.
+func RepeatFnAny(done <-chan any, fn func() any) <-chan any {
+	return RepeatFn(done, fn)
+}
+
+// SleepAny is Sleep over an interface{} stream.
+func SleepAny(done <-chan any, in <-chan any, d time.Duration) <-chan any {
+	return Sleep(done, in, d)
+}
+
+// SleepOrDoneAny is SleepOrDone over an interface{} stream.
+func SleepOrDoneAny(done <-chan any, in <-chan any, d time.Duration) <-chan any {
+	return SleepOrDone(done, in, d)
+}
+
+// BufferAny is Buffer over an interface{} stream.
+func BufferAny(done <-chan any, in <-chan any, n int) <-chan any {
+	return Buffer(done, in, n)
+}
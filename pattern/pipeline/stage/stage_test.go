@@ -2,11 +2,40 @@ package stage
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"math/rand"
 	"testing"
-	"time"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
+// slice send values, then close.
+func slice[T any](done <-chan any, values ...T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for _, v := range values {
+			select {
+			case <-done:
+				return
+			case out <- v:
+			}
+		}
+	}()
+	return out
+}
+
+// collect read in until it's closed.
+func collect[T any](in <-chan T) []T {
+	var got []T
+	for v := range in {
+		got = append(got, v)
+	}
+	return got
+}
+
 func TestTakeFromRepeat(_ *testing.T) {
 	done := make(chan interface{})
 	defer close(done)
@@ -36,9 +65,25 @@ func TestToString(_ *testing.T) {
 	defer close(done)
 
 	var message string
-	for token := range ToString(done, Take(done, Repeat(done, "I", "am."), 5)) {
+	for token := range ToString(done, TakeAny(done, RepeatAny(done, "I", "am."), 5)) {
 		message += token
 	}
 
 	fmt.Println("message:", message)
 }
+
+// TestTyped shows the generic stages keep the element type end to end, no
+// ToString stage needed.
+func TestTyped(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	var message string
+	for token := range Buffer(done, Take(done, Repeat(done, "I", "am."), 5), 2) {
+		message += token
+	}
+
+	if message != "Iam.Iam.I" {
+		t.Errorf("message = %q, want %q", message, "Iam.Iam.I")
+	}
+}
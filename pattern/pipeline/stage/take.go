@@ -0,0 +1,97 @@
+package stage
+
+import (
+	. "learn/go/concurrency/pattern/ordone"
+)
+
+// TakeWhile stage forward items from in as long as pred holds, and close on
+// the first item for which it doesn't (that item is not sent).
+func TakeWhile[T any](done <-chan any, in <-chan T, pred func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for v := range OrDone(done, in) {
+			if !pred(v) {
+				return
+			}
+			select {
+			case <-done:
+				return
+			case out <- v:
+			}
+		}
+	}()
+	return out
+}
+
+// SkipWhile stage drop items from in as long as pred holds, then forward the
+// first item for which it doesn't and everything after it.
+func SkipWhile[T any](done <-chan any, in <-chan T, pred func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		skipping := true
+		for v := range OrDone(done, in) {
+			if skipping && pred(v) {
+				continue
+			}
+			skipping = false
+			select {
+			case <-done:
+				return
+			case out <- v:
+			}
+		}
+	}()
+	return out
+}
+
+// Skip stage drop the first num items from in and forward the rest.
+func Skip[T any](done <-chan any, in <-chan T, num int) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		skipped := 0
+		for v := range OrDone(done, in) {
+			if skipped < num {
+				skipped++
+				continue
+			}
+			select {
+			case <-done:
+				return
+			case out <- v:
+			}
+		}
+	}()
+	return out
+}
+
+// TakeUntil stage forward items from in until until fires (is closed or
+// sends), e.g. another done-like channel, or time.After(d) for a deadline.
+func TakeUntil[T, S any](done <-chan any, in <-chan T, until <-chan S) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-done:
+				return
+			case <-until:
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case <-done:
+					return
+				case <-until:
+					return
+				case out <- v:
+				}
+			}
+		}
+	}()
+	return out
+}
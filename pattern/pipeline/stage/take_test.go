@@ -0,0 +1,54 @@
+package stage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTakeSkip(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	small := func(v int) bool { return v < 3 }
+	for _, tc := range []struct {
+		name string
+		out  <-chan int
+		want string
+	}{
+		{"TakeWhile", TakeWhile(done, slice(done, 1, 2, 3, 1), small), "[1 2]"},
+		{"SkipWhile", SkipWhile(done, slice(done, 1, 2, 3, 1), small), "[3 1]"},
+		{"Skip", Skip(done, slice(done, 1, 2, 3, 1), 3), "[1]"},
+		{"Skip all", Skip(done, slice(done, 1, 2), 3), "[]"},
+		// Take used to send zero values when upstream closed early.
+		{"Take", Take(done, slice(done, 1, 2), 5), "[1 2]"},
+	} {
+		if got := fmt.Sprint(collect(tc.out)); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestTakeUntil(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	stop := make(chan interface{})
+	out := TakeUntil(done, Repeat(done, 1), stop)
+	<-out
+	close(stop)
+	collect(out) // would never return if out was not closed.
+
+	slow := Sleep(done, Repeat(done, 1), 10*time.Millisecond)
+	if n := len(collect(TakeUntil(done, slow, time.After(35*time.Millisecond)))); n < 2 || n > 5 {
+		t.Errorf("got %d items before the deadline, want about 4", n)
+	}
+}
+
+// TestTakeDone shows Take now watch done while waiting for upstream.
+func TestTakeDone(_ *testing.T) {
+	done := make(chan interface{})
+	out := Take(done, make(chan int), 1) // upstream never sends
+	close(done)
+	collect(out)
+}
@@ -0,0 +1,152 @@
+package stage
+
+import (
+	"time"
+)
+
+// ThrottleMode decides which item Throttle lets through in an interval.
+type ThrottleMode int
+
+const (
+	// FirstWins send the first item of an interval right away, and drop the
+	// rest of the interval.
+	FirstWins ThrottleMode = iota
+	// LastWins send the latest item at the end of an interval, the earlier
+	// ones are dropped.
+	LastWins
+)
+
+// Throttle stage send at most one item from in per interval, the others are
+// dropped. mode choose which one is sent, see ThrottleMode.
+//
+// Unlike Sleep, which delays every item and so slows down upstream, Throttle
+// keeps draining in at full speed, and is meant for bursty streams where only
+// the recent state matters.
+func Throttle[T any](
+	done <-chan any,
+	in <-chan T,
+	interval time.Duration,
+	mode ThrottleMode,
+) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+
+		var timer *time.Timer
+		var tick <-chan time.Time // nil when no interval is running.
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+		startInterval := func() {
+			timer = time.NewTimer(interval)
+			tick = timer.C
+		}
+
+		var latest T
+		var pending bool // only used by LastWins.
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-tick:
+				tick = nil
+				if mode == LastWins && pending {
+					select {
+					case <-done:
+						return
+					case out <- latest:
+					}
+					pending = false
+					// Keep the pace: whatever arrive next waits for a
+					// whole interval too.
+					startInterval()
+				}
+			case v, ok := <-in:
+				if !ok {
+					// Don't lose the last word.
+					if mode == LastWins && pending {
+						select {
+						case <-done:
+						case out <- latest:
+						}
+					}
+					return
+				}
+
+				switch mode {
+				case FirstWins:
+					if tick != nil {
+						continue // drop
+					}
+					select {
+					case <-done:
+						return
+					case out <- v:
+					}
+					startInterval()
+				case LastWins:
+					latest, pending = v, true
+					if tick == nil {
+						startInterval()
+					}
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Debounce stage send an item from in only after in has been quiet for quiet
+// duration, i.e. from a burst of items only the last one is sent, once the
+// burst is over. The pending item is sent when in is closed.
+func Debounce[T any](done <-chan any, in <-chan T, quiet time.Duration) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+
+		var timer *time.Timer
+		var settled <-chan time.Time // nil when nothing is pending.
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
+		var latest T
+		for {
+			select {
+			case <-done:
+				return
+			case <-settled:
+				settled = nil
+				select {
+				case <-done:
+					return
+				case out <- latest:
+				}
+			case v, ok := <-in:
+				if !ok {
+					if settled != nil {
+						select {
+						case <-done:
+						case out <- latest:
+						}
+					}
+					return
+				}
+
+				// Every new item restart the quiet period.
+				latest = v
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.NewTimer(quiet)
+				settled = timer.C
+			}
+		}
+	}()
+	return out
+}
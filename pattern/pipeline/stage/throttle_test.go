@@ -0,0 +1,60 @@
+package stage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// burst send values in bursts: the values of a burst back to back, then a gap
+// before the next burst.
+func burst(done <-chan any, gap time.Duration, bursts ...[]int) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for _, b := range bursts {
+			for _, v := range b {
+				select {
+				case <-done:
+					return
+				case out <- v:
+				}
+			}
+			time.Sleep(gap)
+		}
+	}()
+	return out
+}
+
+func TestThrottle(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	bursts := [][]int{{1, 2, 3}, {4, 5, 6}}
+	for _, tc := range []struct {
+		mode ThrottleMode
+		want string
+	}{
+		{FirstWins, "[1 4]"},
+		{LastWins, "[3 6]"},
+	} {
+		in := burst(done, 100*time.Millisecond, bursts...)
+		got := collect(Throttle(done, in, 50*time.Millisecond, tc.mode))
+		if fmt.Sprint(got) != tc.want {
+			t.Errorf("mode %v got %v, want %v", tc.mode, got, tc.want)
+		}
+	}
+}
+
+// TestDebounce simulates button clicks (see sync/cond): a double click and a
+// triple click are each reduced to their last click.
+func TestDebounce(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	clicks := burst(done, 50*time.Millisecond, []int{1, 2}, []int{3, 4, 5})
+	got := collect(Debounce(done, clicks, 20*time.Millisecond))
+	if fmt.Sprint(got) != "[2 5]" {
+		t.Errorf("got %v, want [2 5]", got)
+	}
+}
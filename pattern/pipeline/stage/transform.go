@@ -0,0 +1,121 @@
+package stage
+
+import (
+	. "learn/go/concurrency/pattern/ordone"
+)
+
+// Map stage apply fn to every item from in and send the result to out.
+//
+// It's the library-grade version of the multiply/add stages we hand-rolled in
+// pipeline/main_test.go: the same done discipline as SleepOrDone, but the
+// operation is passed in.
+func Map[T, U any](done <-chan any, in <-chan T, fn func(T) U) <-chan U {
+	out := make(chan U)
+	go func() {
+		defer close(out)
+		for v := range OrDone(done, in) {
+			select {
+			case <-done:
+				return
+			case out <- fn(v):
+			}
+		}
+	}()
+	return out
+}
+
+// Filter stage only let items for which keep return true pass through.
+func Filter[T any](done <-chan any, in <-chan T, keep func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for v := range OrDone(done, in) {
+			if !keep(v) {
+				continue
+			}
+			select {
+			case <-done:
+				return
+			case out <- v:
+			}
+		}
+	}()
+	return out
+}
+
+// FlatMap stage apply fn to every item from in, and send each item of the
+// returned slice to out, in order. fn may return an empty slice to drop v.
+func FlatMap[T, U any](done <-chan any, in <-chan T, fn func(T) []U) <-chan U {
+	out := make(chan U)
+	go func() {
+		defer close(out)
+		for v := range OrDone(done, in) {
+			for _, u := range fn(v) {
+				select {
+				case <-done:
+					return
+				case out <- u:
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Scan stage fold items from in into an accumulator starting from init, and
+// send every intermediate accumulation to out. It's a Reduce which shows its
+// work.
+func Scan[T, A any](
+	done <-chan any,
+	in <-chan T,
+	init A,
+	fn func(acc A, v T) A,
+) <-chan A {
+	out := make(chan A)
+	go func() {
+		defer close(out)
+		acc := init
+		for v := range OrDone(done, in) {
+			acc = fn(acc, v)
+			select {
+			case <-done:
+				return
+			case out <- acc:
+			}
+		}
+	}()
+	return out
+}
+
+// Reduce stage fold all items from in into one value starting from init. The
+// value is sent once in is closed, so Reduce only make sense on a finite
+// stream, e.g. one cut by Take. If done is closed first, nothing is sent.
+func Reduce[T, A any](
+	done <-chan any,
+	in <-chan T,
+	init A,
+	fn func(acc A, v T) A,
+) <-chan A {
+	out := make(chan A)
+	go func() {
+		defer close(out)
+		acc := init
+		for v := range OrDone(done, in) {
+			acc = fn(acc, v)
+		}
+
+		// OrDone close its output on both done and in closing, we have to
+		// tell which one happend.
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		select {
+		case <-done:
+		case out <- acc:
+		}
+	}()
+	return out
+}
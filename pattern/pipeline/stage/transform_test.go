@@ -0,0 +1,73 @@
+package stage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestMapFilter rebuild the multiply/add pipeline from pipeline/main_test.go
+// with Map, plus a Filter to only keep multiples of 3.
+func TestMapFilter(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	ints := Take(done, Repeat(done, 1, 2, 3, 4), 4)
+	multiply := func(v int) int { return v * 2 }
+	add := func(v int) int { return v + 1 }
+	pipeline := Filter(done,
+		Map(done, Map(done, Map(done, ints, multiply), add), multiply),
+		func(v int) bool { return v%3 == 0 },
+	)
+
+	got := collect(pipeline)
+	if fmt.Sprint(got) != "[6 18]" {
+		t.Errorf("got %v, want [6 18]", got)
+	}
+}
+
+func TestFlatMap(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	lines := Take(done, Repeat(done, "a b", "", "c"), 3)
+	words := FlatMap(done, lines, strings.Fields)
+
+	var got []string
+	for w := range words {
+		got = append(got, w)
+	}
+	if strings.Join(got, ",") != "a,b,c" {
+		t.Errorf("got %v, want [a b c]", got)
+	}
+}
+
+func TestScanReduce(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	sum := func(acc, v int) int { return acc + v }
+
+	var sums []int
+	for v := range Scan(done, Take(done, Repeat(done, 1, 2, 3), 3), 0, sum) {
+		sums = append(sums, v)
+	}
+	if fmt.Sprint(sums) != "[1 3 6]" {
+		t.Errorf("Scan got %v, want [1 3 6]", sums)
+	}
+
+	if total := <-Reduce(done, Take(done, Repeat(done, 1, 2, 3), 3), 0, sum); total != 6 {
+		t.Errorf("Reduce got %v, want 6", total)
+	}
+}
+
+// TestReduceDone shows Reduce send nothing when canceled before in is closed.
+func TestReduceDone(t *testing.T) {
+	done := make(chan interface{})
+	out := Reduce(done, Repeat(done, 1), 0, func(acc, v int) int { return acc + v })
+	close(done)
+
+	if v, ok := <-out; ok {
+		t.Errorf("got %v, want out closed without value", v)
+	}
+}
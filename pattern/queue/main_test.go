@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"learn/go/concurrency/internal/leaktest"
 	. "learn/go/concurrency/pattern/pipeline/stage"
 	"log"
 	"os"
@@ -13,6 +14,10 @@ import (
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 // TestPipelineWithoutQueue shows the time efficiency of a pipeline with a slow
 // stage, without the help of a queue/buffer.
 func TestPipelineWithoutQueue(_ *testing.T) {
@@ -74,7 +79,7 @@ func performWrite(b *testing.B, writer io.Writer) {
 	defer close(done)
 
 	b.ResetTimer()
-	for bt := range Take(done, Repeat(done, byte(0)), b.N) {
+	for bt := range TakeAny(done, RepeatAny(done, byte(0)), b.N) {
 		writer.Write([]byte{bt.(byte)})
 	}
 }
@@ -0,0 +1,233 @@
+package tee
+
+import (
+	"reflect"
+)
+
+type policyKind int
+
+const (
+	block policyKind = iota
+	dropNewest
+	dropOldest
+	disconnect
+)
+
+// Policy tells Broadcast what to do with a subscriber which is not ready for
+// the next value.
+type Policy struct {
+	kind policyKind
+	size int
+}
+
+// Block waits for the subscriber, as Tee does: the slowest Block subscriber
+// gates every other subscriber.
+var Block = Policy{kind: block}
+
+// DropNewest buffers up to size values for the subscriber, and drops the
+// value being sent when the buffer is full.
+func DropNewest(size int) Policy {
+	if size < 0 {
+		size = 0
+	}
+	return Policy{kind: dropNewest, size: size}
+}
+
+// DropOldest buffers up to size values (at least one) for the subscriber, and
+// drops the oldest buffered value to make room when the buffer is full, so a
+// slow subscriber always sees the latest values.
+func DropOldest(size int) Policy {
+	if size < 1 {
+		size = 1
+	}
+	return Policy{kind: dropOldest, size: size}
+}
+
+// Disconnect buffers up to size values for the subscriber, and unsubscribes
+// it when the buffer is full: the subscriber sees its channel closed early.
+func Disconnect(size int) Policy {
+	if size < 0 {
+		size = 0
+	}
+	return Policy{kind: disconnect, size: size}
+}
+
+type subscriber[T any] struct {
+	c      chan T
+	policy Policy
+}
+
+// Broadcaster send every value of its input to all its subscribers, see
+// Broadcast.
+type Broadcaster[T any] struct {
+	// ops are run by the broadcasting goroutine, which alone owns subs. This
+	// is how Subscribe and Unsubscribe change subs without a lock.
+	ops  chan func()
+	quit chan any
+	subs map[<-chan T]*subscriber[T]
+}
+
+// Broadcast is Tee generalized to n outputs, each with its own Policy: the
+// ith output uses policies[i], or Block if there is none. More subscribers
+// can be added or removed at runtime by the returned Broadcaster.
+//
+// Block subscribers are written the way Tee does it, with one select over
+// all of them. Since their number changes at runtime, the select is built
+// with reflect.Select.
+//
+// All subscribers are closed when in is closed or done is closed.
+func Broadcast[T any](
+	done <-chan any,
+	in <-chan T,
+	n int,
+	policies ...Policy,
+) (*Broadcaster[T], []<-chan T) {
+	b := &Broadcaster[T]{
+		ops:  make(chan func()),
+		quit: make(chan any),
+		subs: make(map[<-chan T]*subscriber[T]),
+	}
+
+	outs := make([]<-chan T, n)
+	for i := range outs {
+		policy := Block
+		if i < len(policies) {
+			policy = policies[i]
+		}
+		outs[i] = b.add(policy)
+	}
+
+	go func() {
+		defer close(b.quit)
+		defer func() {
+			for _, s := range b.subs {
+				close(s.c)
+			}
+		}()
+		for {
+			select {
+			case <-done:
+				return
+			case op := <-b.ops:
+				op()
+			case v, ok := <-in:
+				if !ok || !b.send(done, v) {
+					return
+				}
+			}
+		}
+	}()
+	return b, outs
+}
+
+// Subscribe add a subscriber with policy. It receives every value read from
+// the input after Subscribe returns. If the Broadcaster is already finished,
+// the returned channel is closed.
+func (b *Broadcaster[T]) Subscribe(policy Policy) <-chan T {
+	// An op received is run right away by the broadcasting goroutine, so
+	// waiting for its result can't block past quit.
+	added := make(chan (<-chan T), 1)
+	select {
+	case b.ops <- func() { added <- b.add(policy) }:
+		return <-added
+	case <-b.quit:
+		closed := make(chan T)
+		close(closed)
+		return closed
+	}
+}
+
+// Unsubscribe remove the subscriber c and close it, values already buffered
+// for it can still be read. It's a no-op if c is not subscribed anymore.
+func (b *Broadcaster[T]) Unsubscribe(c <-chan T) {
+	removed := make(chan any)
+	select {
+	case b.ops <- func() { b.remove(c); close(removed) }:
+		<-removed
+	case <-b.quit:
+	}
+}
+
+func (b *Broadcaster[T]) add(policy Policy) <-chan T {
+	s := &subscriber[T]{c: make(chan T, policy.size), policy: policy}
+	b.subs[s.c] = s
+	return s.c
+}
+
+func (b *Broadcaster[T]) remove(c <-chan T) {
+	if s, ok := b.subs[c]; ok {
+		delete(b.subs, c)
+		close(s.c)
+	}
+}
+
+// send v to every subscriber according to its policy. It returns false if
+// done was closed meanwhile.
+func (b *Broadcaster[T]) send(done <-chan any, v T) bool {
+	var blocking []*subscriber[T]
+	for _, s := range b.subs {
+		switch s.policy.kind {
+		case block:
+			blocking = append(blocking, s)
+		case dropNewest:
+			select {
+			case s.c <- v:
+			default:
+			}
+		case dropOldest:
+			select {
+			case s.c <- v:
+			default:
+				// We are the only sender, so once one value is taken out
+				// (by us or the subscriber) the send can't fail.
+				select {
+				case <-s.c:
+				default:
+				}
+				s.c <- v
+			}
+		case disconnect:
+			select {
+			case s.c <- v:
+			default:
+				b.remove(s.c)
+			}
+		}
+	}
+
+	for len(blocking) > 0 {
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(done)},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(b.ops)},
+		}
+		for _, s := range blocking {
+			cases = append(cases, reflect.SelectCase{
+				Dir:  reflect.SelectSend,
+				Chan: reflect.ValueOf(s.c),
+				Send: reflect.ValueOf(&v).Elem(), // Valid even for a nil interface v.
+			})
+		}
+
+		chosen, op, _ := reflect.Select(cases)
+		switch chosen {
+		case 0:
+			return false
+		case 1:
+			// Keep serving Subscribe and Unsubscribe while blocked, so a
+			// stuck Block subscriber can still be unsubscribed. Those who
+			// were unsubscribed meanwhile don't get v anymore.
+			op.Interface().(func())()
+			var still []*subscriber[T]
+			for _, s := range blocking {
+				if b.subs[s.c] == s {
+					still = append(still, s)
+				}
+			}
+			blocking = still
+		default:
+			// Like Tee setting the written channel to nil.
+			blocking = append(blocking[:chosen-2], blocking[chosen-1:]...)
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package tee
+
+import (
+	"fmt"
+	"testing"
+)
+
+// collect read c until it's closed.
+func collect[T any](c <-chan T) []T {
+	var got []T
+	for v := range c {
+		got = append(got, v)
+	}
+	return got
+}
+
+func TestBroadcastPolicies(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := 0; i < 5; i++ {
+			in <- i
+		}
+	}()
+
+	// Only the Block subscriber is read while broadcasting, the others are
+	// read after everything was sent, as the slowest possible subscribers.
+	// Negative sizes are taken as 0: only a subscriber ready right away gets
+	// the value, never the case here.
+	_, outs := Broadcast(done, in, 6, Block, DropNewest(2), DropOldest(2), Disconnect(1), DropNewest(-1), Disconnect(-1))
+	for i, want := range []string{"[0 1 2 3 4]", "[0 1]", "[3 4]", "[0]", "[]", "[]"} {
+		if got := fmt.Sprint(collect(outs[i])); got != want {
+			t.Errorf("outs[%d] got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestBroadcastSubscribe(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	in := make(chan int)
+	b, outs := Broadcast(done, in, 1)
+
+	in <- 1
+	if v := <-outs[0]; v != 1 {
+		t.Errorf("got %v, want 1", v)
+	}
+
+	c := b.Subscribe(Block)
+	in <- 2
+	if v1, v2 := <-outs[0], <-c; v1 != 2 || v2 != 2 {
+		t.Errorf("got %v and %v, want 2 for both", v1, v2)
+	}
+
+	// c is not read anymore, which would block outs[0] if it stayed.
+	in <- 3
+	b.Unsubscribe(c)
+	if v := <-outs[0]; v != 3 {
+		t.Errorf("got %v, want 3", v)
+	}
+	if _, ok := <-c; ok {
+		t.Error("c not closed after Unsubscribe")
+	}
+
+	close(in)
+	if _, ok := <-outs[0]; ok {
+		t.Error("outs[0] not closed after in")
+	}
+	if _, ok := <-b.Subscribe(Block); ok {
+		t.Error("Subscribe after the end got an open channel")
+	}
+}
@@ -0,0 +1,45 @@
+package tee
+
+import (
+	. "learn/go/concurrency/pattern/ordone"
+)
+
+// Tee implements the tee-channel pattern: every value read from in is sent to
+// both outputs. The slower reader gates the other, see Broadcast for more
+// outputs and for ways around that.
+func Tee[T any](
+	done <-chan any,
+	in <-chan T,
+) (_, _ <-chan T) {
+	out1 := make(chan T)
+	out2 := make(chan T)
+	go func() {
+		defer close(out1)
+		defer close(out2)
+		for v := range OrDone(done, in) {
+			// We shadow out1 and out2 because we need to set them to nil
+			// after sending one value into them. See below.
+			var out1, out2 = out1, out2
+
+			// We use one select statement so that writes to out1 and out2
+			// don’t block each other.
+			//
+			// To ensure both are written to, we’ll perform "two" iterations
+			// of the select statement: one for each outbound channel.
+			for i := 0; i < 2; i++ {
+				select {
+				case <-done:
+					return
+				// Once one of them is written, it's set to nil, so the next
+				// iteration will have to write to the other. Hence we got
+				// both out1 and out2 written.
+				case out1 <- v:
+					out1 = nil
+				case out2 <- v:
+					out2 = nil
+				}
+			}
+		}
+	}()
+	return out1, out2
+}
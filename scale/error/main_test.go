@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"log"
 	"os"
 	"os/exec"
@@ -9,6 +10,10 @@ import (
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 type MyError struct {
 	Inner      error
 	Message    string
@@ -1,8 +1,10 @@
 package steward
 
 import (
+	"fmt"
 	. "learn/go/concurrency/pattern/or-channel"
 	"log"
+	"math/rand"
 	"time"
 )
 
@@ -25,9 +27,111 @@ type startGoroutineFn func(
 // * newSteward is a func which receive a func and return a func of the same type.
 // * the other parameter 'timeout', is used to configure the returned func.
 // * the real word is done by users calling the configured func.
+//
+// The ward is restarted right away, and forever. See newStewardWithPolicy for
+// backoff and giving up.
 func newSteward(
 	timeout time.Duration,
 	startGoroutine startGoroutineFn,
+) startGoroutineFn {
+	return newStewardWithPolicy(timeout, restartPolicy{}, startGoroutine)
+}
+
+// restartPolicy tells a steward how to restart its ward. The zero value
+// restarts it right away, forever, as newSteward does.
+//
+// Restarting right away is fine for a ward which crashed on a bad input, but
+// if it's unhealthy because a dependency is down, restarting it again and
+// again only hammers that dependency. Hence backoff and jitter. And a ward
+// which can't stay up is better reported than restarted forever, hence
+// restart intensity, as Erlang/OTP supervisors do.
+type restartPolicy struct {
+	// backoff is the delay before a restart, doubled for every other restart
+	// within window, up to maxBackoff (defaultMaxBackoff if zero).
+	backoff    time.Duration
+	maxBackoff time.Duration
+
+	// jitter randomizes every delay by up to ±jitter of it, e.g. 0.2, so
+	// stewards failing together don't restart in lockstep.
+	jitter float64
+
+	// maxRestarts is how many restarts are allowed within window (all of
+	// them if window is zero). One more and the steward gives up. Zero means
+	// no limit.
+	maxRestarts int
+	window      time.Duration
+}
+
+// defaultMaxBackoff caps backoff when maxBackoff is zero. Doubling with no
+// cap would overflow time.Duration after a few dozen restarts, wrapping to a
+// negative delay, i.e. restarting right away again.
+const defaultMaxBackoff = 5 * time.Minute
+
+func (p restartPolicy) ceiling() time.Duration {
+	if p.maxBackoff > 0 {
+		return p.maxBackoff
+	}
+	return defaultMaxBackoff
+}
+
+// delay return how long to wait before a restart, given the number of other
+// restarts within window.
+func (p restartPolicy) delay(restarts int) time.Duration {
+	d, ceiling := p.backoff, p.ceiling()
+	for i := 0; i < restarts && d > 0 && d < ceiling; i++ {
+		if d > ceiling/2 {
+			d = ceiling // Saturate, d*2 could overflow.
+			break
+		}
+		d *= 2
+	}
+	if d > ceiling {
+		d = ceiling
+	}
+	if p.jitter > 0 {
+		d += time.Duration(p.jitter * (2*rand.Float64() - 1) * float64(d))
+	}
+	return d
+}
+
+// remembered return how many past restarts are worth keeping: enough to
+// check maxRestarts, and to double backoff up to its ceiling. The zero policy
+// needs none, so newSteward doesn't keep one timestamp per restart forever.
+func (p restartPolicy) remembered() int {
+	n := p.maxRestarts
+	doublings := 0
+	for d, ceiling := p.backoff, p.ceiling(); d > 0 && d < ceiling; d *= 2 {
+		doublings++
+		if d > ceiling/2 {
+			break
+		}
+	}
+	if doublings > n {
+		n = doublings
+	}
+	return n
+}
+
+// restartIntensityError is sent on a steward's heartbeat, right before it's
+// closed, when the steward gave up on its ward.
+type restartIntensityError struct {
+	maxRestarts int
+	window      time.Duration
+}
+
+func (err restartIntensityError) Error() string {
+	return fmt.Sprintf("ward restarted more than %d times within %v", err.maxRestarts, err.window)
+}
+
+// newStewardWithPolicy is newSteward which restarts the ward according to
+// policy. When the ward needs more restarts than policy allows, the steward
+// halts the ward and escalates: it sends a restartIntensityError on its own
+// heartbeat, then closes it. If the steward is itself monitored by another
+// steward, that one will then restart it, according to its own policy.
+func newStewardWithPolicy(
+	timeout time.Duration,
+	policy restartPolicy,
+	startGoroutine startGoroutineFn,
 ) startGoroutineFn {
 	return func(
 		done <-chan any,
@@ -50,6 +154,65 @@ func newSteward(
 			startWard()
 
 			pulse := time.Tick(pulseInterval)
+			sendPulse := func() {
+				select {
+				case heartbeat <- struct{}{}:
+				default:
+				}
+			}
+
+			// restarts are the times of the last restarts within
+			// policy.window, at most policy.remembered() of them.
+			var restarts []time.Time
+
+			// restartWard wait for the backoff delay, still pulsing, then
+			// restart the ward. It return false if the steward must halt.
+			restartWard := func() bool {
+				close(wardDone)
+
+				now := time.Now()
+				if policy.window > 0 {
+					for len(restarts) > 0 && now.Sub(restarts[0]) > policy.window {
+						restarts = restarts[1:]
+					}
+				}
+				if policy.maxRestarts > 0 && len(restarts) >= policy.maxRestarts {
+					err := restartIntensityError{policy.maxRestarts, policy.window}
+					log.Printf("steward: %v; giving up", err)
+					select {
+					case heartbeat <- err:
+					case <-done:
+					}
+					return false
+				}
+
+				delay := policy.delay(len(restarts))
+				if keep := policy.remembered(); keep > 0 {
+					restarts = append(restarts, now)
+					if len(restarts) > keep {
+						restarts = restarts[len(restarts)-keep:]
+					}
+				}
+				if delay <= 0 {
+					log.Println("steward: ward unhealthy; restarting")
+				} else {
+					log.Printf("steward: ward unhealthy; restarting in %v", delay)
+					wait := time.After(delay)
+				waitLoop:
+					for {
+						select {
+						case <-pulse:
+							sendPulse()
+						case <-wait:
+							break waitLoop
+						case <-done:
+							return false
+						}
+					}
+				}
+				startWard()
+				return true
+			}
 
 		monitorLoop:
 			for {
@@ -60,16 +223,20 @@ func newSteward(
 				for {
 					select {
 					case <-pulse:
-						select {
-						case heartbeat <- struct{}{}:
-						default:
+						sendPulse()
+					case _, ok := <-wardHeartbeat:
+						if !ok {
+							// The ward gave up (e.g. it's a steward itself),
+							// a closed channel must not count as pulses. Let
+							// it time out.
+							wardHeartbeat = nil
+							continue
 						}
-					case <-wardHeartbeat:
 						continue monitorLoop
 					case <-timeoutSignal:
-						log.Println("steward: ward unhealthy; restarting")
-						close(wardDone)
-						startWard()
+						if !restartWard() {
+							return
+						}
 						continue monitorLoop
 					case <-done:
 						return
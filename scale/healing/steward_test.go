@@ -1,12 +1,18 @@
 package steward
 
 import (
+	"learn/go/concurrency/internal/leaktest"
 	"log"
+	"math"
 	"os"
 	"testing"
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 // TestSteward shows how steward monitor and restart a unhealthy ward 'doWork'.
 func TestSteward(_ *testing.T) {
 	log.SetOutput(os.Stdout)
@@ -36,4 +42,86 @@ func TestSteward(_ *testing.T) {
 	log.Println("Done.")
 }
 
+// TestStewardGiveUp shows a steward backing off between restarts, then giving
+// up on a ward which keeps being unhealthy.
+func TestStewardGiveUp(t *testing.T) {
+	log.SetOutput(os.Stdout)
+	log.SetFlags(log.Ltime | log.LUTC)
+
+	starts := 0
+	doWork := func(done <-chan any, _ time.Duration) <-chan any {
+		starts++ // Only the steward goroutine starts the ward.
+		return nil
+	}
+	policy := restartPolicy{
+		backoff:     10 * time.Millisecond,
+		maxBackoff:  20 * time.Millisecond,
+		maxRestarts: 3,
+		window:      time.Minute,
+	}
+	doWorkWithSteward := newStewardWithPolicy(10*time.Millisecond, policy, doWork)
+
+	done := make(chan any)
+	defer close(done)
+
+	start := time.Now()
+	var last any
+	for hb := range doWorkWithSteward(done, time.Hour) {
+		last = hb
+	}
+	if _, ok := last.(restartIntensityError); !ok {
+		t.Errorf("got last heartbeat %v, want a restartIntensityError", last)
+	}
+	if starts != 4 {
+		t.Errorf("ward started %d times, want 4", starts)
+	}
+	// 4 timeouts of 10ms, and backoffs of 10ms, 20ms and 20ms.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("gave up after %v, want at least 90ms", elapsed)
+	}
+}
+
+func TestRestartPolicyDelay(t *testing.T) {
+	p := restartPolicy{backoff: time.Second, maxBackoff: 5 * time.Second}
+	for restarts, want := range []time.Duration{1, 2, 4, 5, 5} {
+		if d := p.delay(restarts); d != want*time.Second {
+			t.Errorf("delay(%d) = %v, want %v", restarts, d, want*time.Second)
+		}
+	}
+
+	p.jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := p.delay(0); d < 500*time.Millisecond || d > 1500*time.Millisecond {
+			t.Fatalf("delay(0) = %v with jitter 0.5, want within 1s±50%%", d)
+		}
+	}
+}
+
+// TestRestartPolicyDelayNoOverflow shows backoff saturating instead of
+// doubling past what time.Duration holds.
+func TestRestartPolicyDelayNoOverflow(t *testing.T) {
+	p := restartPolicy{backoff: time.Second}
+	if d := p.delay(1000); d != defaultMaxBackoff {
+		t.Errorf("delay(1000) = %v, want %v", d, defaultMaxBackoff)
+	}
+	p.maxBackoff = math.MaxInt64
+	if d := p.delay(1000); d <= 0 || d > p.maxBackoff {
+		t.Errorf("delay(1000) = %v, want within (0, %v]", d, p.maxBackoff)
+	}
+}
 
+func TestRestartPolicyRemembered(t *testing.T) {
+	for _, c := range []struct {
+		p    restartPolicy
+		want int
+	}{
+		{restartPolicy{}, 0},
+		{restartPolicy{maxRestarts: 3}, 3},
+		{restartPolicy{backoff: time.Second, maxBackoff: 8 * time.Second}, 3},
+		{restartPolicy{backoff: time.Second, maxBackoff: 8 * time.Second, maxRestarts: 5}, 5},
+	} {
+		if got := c.p.remembered(); got != c.want {
+			t.Errorf("%+v.remembered() = %d, want %d", c.p, got, c.want)
+		}
+	}
+}
@@ -2,10 +2,15 @@ package main
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"testing"
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 // doWork implement interval-based heartbeat pattern. It will wait for incoming
 // unit of works, process it and send out the result. In the mean time, it also
 // sends a pulse every pulseInterval.
@@ -2,10 +2,15 @@ package main
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"testing"
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 // doWork implements the "Heartbeat when work start" pattern. It send a pulse
 // before every processing of work unit.
 func doWork(done <-chan any, works ...int) (<-chan any, <-chan int) {
@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"learn/go/concurrency/internal/leaktest"
 	"log"
 	"os"
 	"sync"
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 type APIConnection struct{}
 
 func Open() *APIConnection {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"learn/go/concurrency/internal/leaktest"
 	"log"
 	"os"
 	"sort"
@@ -12,6 +13,10 @@ import (
 	"golang.org/x/time/rate"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 // Per limit n events per duration.
 func Per(n int, duration time.Duration) rate.Limit {
 	return rate.Every(duration / time.Duration(n))
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"learn/go/concurrency/internal/leaktest"
 	"log"
 	"os"
 	"sync"
@@ -10,6 +11,10 @@ import (
 	"golang.org/x/time/rate"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 // APIConnection shows how a one-tier rate limiter works. It use x/time/rate
 // package which provide a token bucket rate limiter.
 //
@@ -2,12 +2,17 @@ package main
 
 import (
 	"fmt"
+	"learn/go/concurrency/internal/leaktest"
 	"math/rand"
 	"sync"
 	"testing"
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	leaktest.Main(m)
+}
+
 // doWork is our worker that may have different delay doing work due to
 // different loads, network latency...
 func doWork(done <-chan any, id int, wg *sync.WaitGroup, result chan<- int) {